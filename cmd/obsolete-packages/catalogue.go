@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/omilevskyi/go/pkg/portver"
+)
+
+// catalogueName - unpacked repository catalogue, one JSON object per package and line
+const catalogueName = "packagesite.yaml"
+
+// CatalogueEntry - package described in the repository catalogue
+type CatalogueEntry struct {
+	Name        string            `json:"name"`
+	Origin      string            `json:"origin"`
+	Version     string            `json:"version"`
+	ABI         string            `json:"abi"`
+	Path        string            `json:"path"`
+	RepoPath    string            `json:"repopath"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// File returns the path of the package file relative to the repository root.
+func (e *CatalogueEntry) File() string {
+	if e.RepoPath != "" {
+		return filepath.Clean(e.RepoPath)
+	}
+	return filepath.Clean(e.Path)
+}
+
+// FullOrigin returns the port origin with the flavor if any, e.g. "devel/py-setuptools@py311".
+func (e *CatalogueEntry) FullOrigin() string {
+	if flavor := e.Annotations["flavor"]; flavor != "" {
+		return e.Origin + "@" + flavor
+	}
+	return e.Origin
+}
+
+// readCatalogue reads catalogue entries keyed by the package file relative to the repository root.
+func readCatalogue(r io.Reader) (map[string]*CatalogueEntry, error) {
+	entries, lineCount := map[string]*CatalogueEntry{}, 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024) // entries list all files of a package
+	for scanner.Scan() {
+		lineCount++
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		entry := &CatalogueEntry{}
+		if err := json.Unmarshal(line, entry); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineCount, err)
+		}
+		if entry.Name == "" || entry.Version == "" || entry.File() == "." {
+			return nil, fmt.Errorf("line %d: incomplete entry", lineCount)
+		}
+		entries[entry.File()] = entry
+	}
+	return entries, scanner.Err()
+}
+
+// catalogue - entries of a single repository and the files seen so far
+type catalogue struct {
+	root    string
+	entries map[string]*CatalogueEntry
+	seen    map[string]struct{}
+}
+
+// Catalogues finds, reads and caches repository catalogues; it is safe for concurrent use.
+// Every catalogue is read once, concurrent lookups in other directories do not wait for it.
+type Catalogues struct {
+	mu      sync.Mutex
+	byDir   map[[2]string]*dirCatalogue  // {directory, top} -> the nearest catalogue
+	byRoot  map[string]*dirCatalogue     // directory -> its own catalogue
+	Invalid func(path string, err error) // called for catalogues that cannot be read, or nil; never concurrently
+}
+
+// dirCatalogue - a catalogue, or nil, determined once
+type dirCatalogue struct {
+	once sync.Once
+	cat  *catalogue
+}
+
+// NewCatalogues -
+func NewCatalogues() *Catalogues {
+	return &Catalogues{byDir: map[[2]string]*dirCatalogue{}, byRoot: map[string]*dirCatalogue{}}
+}
+
+// cached returns the entry of the key, adding an empty one if there is none.
+func cached[K comparable](mu *sync.Mutex, m map[K]*dirCatalogue, key K) *dirCatalogue {
+	mu.Lock()
+	defer mu.Unlock()
+	d, ok := m[key]
+	if !ok {
+		d = &dirCatalogue{}
+		m[key] = d
+	}
+	return d
+}
+
+// read returns the catalogue of the directory itself, or nil.
+func (c *Catalogues) read(dir string) *catalogue {
+	d := cached(&c.mu, c.byRoot, dir)
+	d.once.Do(func() {
+		path := filepath.Join(dir, catalogueName)
+		f, err := os.Open(path)
+		if err == nil {
+			var entries map[string]*CatalogueEntry
+			entries, err = readCatalogue(f)
+			_ = f.Close()
+			if err == nil {
+				d.cat = &catalogue{root: dir, entries: entries, seen: map[string]struct{}{}}
+				return
+			}
+		}
+		if !errors.Is(err, os.ErrNotExist) && c.Invalid != nil {
+			c.mu.Lock()
+			c.Invalid(path, err)
+			c.mu.Unlock()
+		}
+	})
+	return d.cat
+}
+
+// find returns the catalogue of the nearest directory (dir itself or its parent up to top) having one.
+func (c *Catalogues) find(dir, top string) *catalogue {
+	d := cached(&c.mu, c.byDir, [2]string{dir, top})
+	d.once.Do(func() {
+		d.cat = c.read(dir)
+		if parent := filepath.Dir(dir); d.cat == nil && dir != top && parent != dir {
+			d.cat = c.find(parent, top)
+		}
+	})
+	return d.cat
+}
+
+// Lookup returns the package name and version of the file located under top according to
+// the repository catalogue, or an empty name if the file is not catalogued.
+func (c *Catalogues) Lookup(path, top string) (string, *VersionType) {
+	cat := c.find(filepath.Dir(path), top)
+	if cat == nil {
+		return "", nil
+	}
+	rel, err := filepath.Rel(cat.root, path)
+	if err != nil {
+		return "", nil
+	}
+	entry, ok := cat.entries[rel]
+	if !ok {
+		return "", nil
+	}
+	v, err := portver.Parse(entry.Version)
+	if err != nil {
+		return "", nil
+	}
+	c.mu.Lock()
+	cat.seen[rel] = struct{}{}
+	c.mu.Unlock()
+	return entry.Name, &VersionType{Path: filepath.Clean(path), Version: v, Origin: entry.FullOrigin(), ABI: entry.ABI}
+}
+
+// Missing returns "name-version path" of catalogued packages whose files do not exist, sorted.
+func (c *Catalogues) Missing() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var missing []string
+	for _, d := range c.byRoot {
+		cat := d.cat
+		if cat == nil {
+			continue
+		}
+		for rel, entry := range cat.entries {
+			if _, ok := cat.seen[rel]; ok {
+				continue
+			}
+			if path := filepath.Join(cat.root, rel); !exists(path) {
+				missing = append(missing, entry.Name+"-"+entry.Version+" "+path)
+			}
+		}
+	}
+	slices.Sort(missing)
+	return missing
+}
+
+// exists reports whether anything exists at the path.
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
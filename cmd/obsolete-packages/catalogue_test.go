@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testCatalogue = `{"name":"py311-setuptools","origin":"devel/py-setuptools","version":"63.1.0_1","abi":"FreeBSD:14:amd64","repopath":"All/py311-setuptools-63.1.0_1.pkg","annotations":{"flavor":"py311"}}
+{"name":"openssl-3","origin":"security/openssl-3","version":"3.0.15,1","abi":"FreeBSD:14:amd64","repopath":"All/openssl-3-3.0.15,1.pkg"}
+{"name":"gone","origin":"misc/gone","version":"1.0","abi":"FreeBSD:14:amd64","path":"All/gone-1.0.pkg"}
+`
+
+func TestReadCatalogue(t *testing.T) {
+	entries, err := readCatalogue(strings.NewReader(testCatalogue + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("readCatalogue() returned %d entries", len(entries))
+	}
+	if e := entries[filepath.Join("All", "py311-setuptools-63.1.0_1.pkg")]; e == nil || e.FullOrigin() != "devel/py-setuptools@py311" {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+	if e := entries[filepath.Join("All", "gone-1.0.pkg")]; e == nil || e.FullOrigin() != "misc/gone" {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+
+	for _, invalid := range []string{"{", `{"name":"x","version":"1.0"}`} {
+		if _, err := readCatalogue(strings.NewReader(invalid)); err == nil {
+			t.Errorf("readCatalogue(%q) expected an error", invalid)
+		}
+	}
+}
+
+func TestScanCatalogue(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "FreeBSD:14:amd64")
+	writeTestFiles(t, repo,
+		"All/py311-setuptools-63.1.0_1.pkg",
+		"All/openssl-3-3.0.15,1.pkg",
+		"All/openssl-3-3.0.14,1.pkg", // not catalogued
+	)
+	if err := os.WriteFile(filepath.Join(repo, catalogueName), []byte(testCatalogue), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	catalogues := NewCatalogues()
+	data, _ := (&Scanner{Jobs: 2, Catalogues: catalogues}).Scan([]string{root})
+
+	got := map[string][]string{}
+	for k, versions := range data {
+		for _, ver := range *versions {
+			got[k] = append(got[k], ver.String()+" "+ver.Origin+" "+ver.ABI)
+		}
+	}
+	want := map[string][]string{
+		"py311-setuptools": {"63.1.0_1 devel/py-setuptools@py311 FreeBSD:14:amd64"},
+		"openssl-3":        {"3.0.14,1  ", "3.0.15,1 security/openssl-3 FreeBSD:14:amd64"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Scan() = %q, want %q", got, want)
+	}
+
+	if missing := catalogues.Missing(); !reflect.DeepEqual(missing, []string{"gone-1.0 " + filepath.Join(repo, "All", "gone-1.0.pkg")}) {
+		t.Errorf("Missing() = %q", missing)
+	}
+}
+
+func TestCatalogueInvalid(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, "All/bash-5.2.37.pkg", catalogueName)
+
+	var invalid []string
+	catalogues := NewCatalogues()
+	catalogues.Invalid = func(path string, err error) { invalid = append(invalid, path) }
+	data, _ := (&Scanner{Jobs: 1, Catalogues: catalogues}).Scan([]string{root})
+	if _, ok := data["bash"]; !ok {
+		t.Errorf("Scan() = %v", data)
+	}
+	if !reflect.DeepEqual(invalid, []string{filepath.Join(root, catalogueName)}) {
+		t.Errorf("invalid catalogues: %q", invalid)
+	}
+}
+
+func TestCataloguesConcurrent(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "FreeBSD:14:amd64")
+	writeTestFiles(t, repo, "All/openssl-3-3.0.15,1.pkg", "All/gone-1.0.pkg", "broken/All/bash-5.2.37.pkg", "broken/"+catalogueName)
+	if err := os.WriteFile(filepath.Join(repo, catalogueName), []byte(testCatalogue), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var invalid []string
+	catalogues := NewCatalogues()
+	catalogues.Invalid = func(path string, err error) { invalid = append(invalid, path) }
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for _, name := range []string{"All/openssl-3-3.0.15,1.pkg", "All/gone-1.0.pkg", "broken/All/bash-5.2.37.pkg"} {
+				catalogues.Lookup(filepath.Join(repo, name), root)
+			}
+		})
+	}
+	wg.Wait()
+
+	if missing := catalogues.Missing(); !reflect.DeepEqual(missing, []string{"py311-setuptools-63.1.0_1 " + filepath.Join(repo, "All", "py311-setuptools-63.1.0_1.pkg")}) {
+		t.Errorf("Missing() = %q", missing)
+	}
+	if !reflect.DeepEqual(invalid, []string{filepath.Join(repo, "broken", catalogueName)}) {
+		t.Errorf("invalid catalogues: %q", invalid)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"syscall"
+)
+
+// errCrossDevice - the duplicate is on another filesystem than its original, so it cannot be hardlinked
+var errCrossDevice = errors.New("on a different filesystem")
+
+// Duplicate - package file byte-identical to another one with the same name
+type Duplicate struct {
+	Path     string
+	Original string
+	Size     int64
+}
+
+// fileHash returns the hex-encoded SHA-256 of the file contents.
+func fileHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	// nolint:errcheck
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// findDuplicates looks for package files with the same file name in different directories,
+// compares sizes first and SHA-256 of the contents only for files of the same size.
+// Of identical files the first one by path is the original; files already hardlinked to it are skipped.
+// Duplicates are returned sorted by path.
+func findDuplicates(data map[string]*[]VersionType) ([]Duplicate, error) {
+	type candidate struct {
+		name string
+		size int64
+	}
+	candidates := map[candidate][]string{}
+	for _, versions := range data {
+		for _, ver := range *versions {
+			c := candidate{filepath.Base(ver.Path), ver.Size}
+			if !slices.Contains(candidates[c], ver.Path) {
+				candidates[c] = append(candidates[c], ver.Path)
+			}
+		}
+	}
+
+	var duplicates []Duplicate
+	var errs []error
+	for c, paths := range candidates {
+		if len(paths) < 2 {
+			continue
+		}
+		slices.Sort(paths)
+
+		originals := map[string]string{} // SHA-256 -> the first path
+		for _, path := range paths {
+			sum, err := fileHash(path)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			original, ok := originals[sum]
+			if !ok {
+				originals[sum] = path
+				continue
+			}
+			if sameFile(original, path) {
+				continue // already hardlinked
+			}
+			duplicates = append(duplicates, Duplicate{Path: path, Original: original, Size: c.size})
+		}
+	}
+
+	slices.SortFunc(duplicates, func(a, b Duplicate) int { return cmp.Compare(a.Path, b.Path) })
+	return duplicates, errors.Join(errs...)
+}
+
+// sameFile reports whether both paths refer to the same file.
+func sameFile(a, b string) bool {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bi, err := os.Stat(b)
+	return err == nil && os.SameFile(ai, bi)
+}
+
+// hardlink atomically replaces the file at path with a hard link to original.
+// It returns errCrossDevice if both are not on the same filesystem.
+func hardlink(original, path string) error {
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp"+strconv.Itoa(os.Getpid()))
+	if err := os.Link(original, tmp); err != nil {
+		if errors.Is(err, syscall.EXDEV) {
+			return errCrossDevice
+		}
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// processDuplicates writes "duplicate = original" lines to w, or replaces duplicates with hard links
+// when apply is true (then lines are written only when verbose). Duplicates on other filesystems
+// are reported to errw and skipped. It returns the number of duplicates and their total size,
+// counting only those hardlinked when apply is true.
+func processDuplicates(w, errw io.Writer, duplicates []Duplicate, apply, verbose bool) (int, int64, error) {
+	count, size, errs := 0, int64(0), []error(nil)
+	for _, d := range duplicates {
+		if apply {
+			if err := hardlink(d.Original, d.Path); err != nil {
+				if errors.Is(err, errCrossDevice) {
+					_, _ = fmt.Fprintf(errw, "Skipped duplicate %s: %v\n", d.Path, err)
+				} else {
+					errs = append(errs, fmt.Errorf("%s: %w", d.Path, err))
+				}
+				continue
+			}
+		}
+		if !apply || verbose {
+			_, _ = fmt.Fprintln(w, d.Path, "=", d.Original)
+		}
+		count, size = count+1, size+d.Size
+	}
+	return count, size, errors.Join(errs...)
+}
+
+// duplicatesSummary -
+func duplicatesSummary(count int, size int64, applied bool) string {
+	if applied {
+		return fmt.Sprintf("%d duplicate(s) hardlinked, %s reclaimed", count, humanSize(size))
+	}
+	return fmt.Sprintf("%d duplicate(s), %s reclaimable", count, humanSize(size))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDuplicates(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a/All/bash-5.2.37.pkg", "bash")
+	write("b/All/bash-5.2.37.pkg", "bash")
+	write("c/All/bash-5.2.37.pkg", "BASH") // same size, different contents
+	write("a/All/pkg-1.21.3.pkg", "pkg")
+	write("b/All/pkg-1.21.3.pkg", "pkg 1.21.3") // different size
+	write("a/All/curl-8.11.1.pkg", "curl")
+	if err := os.Link(filepath.Join(root, "a/All/curl-8.11.1.pkg"), filepath.Join(root, "b/All/curl-8.11.1.pkg")); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := (&Scanner{Jobs: 2, Finder: NewRepositoryFinder()}).Scan([]string{root})
+	duplicates, err := findDuplicates(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Duplicate{Path: filepath.Join(root, "b/All/bash-5.2.37.pkg"), Original: filepath.Join(root, "a/All/bash-5.2.37.pkg"), Size: 4}
+	if len(duplicates) != 1 || duplicates[0] != want {
+		t.Fatalf("findDuplicates() = %+v, want [%+v]", duplicates, want)
+	}
+
+	var out, errOut bytes.Buffer
+	count, size, err := processDuplicates(&out, &errOut, duplicates, false, false)
+	if err != nil || count != 1 || size != 4 || out.String() != want.Path+" = "+want.Original+"\n" {
+		t.Errorf("processDuplicates() = %d, %d, %v; output %q", count, size, err, out.String())
+	}
+	if sameFile(want.Path, want.Original) {
+		t.Fatal("dry run hardlinked the duplicate")
+	}
+
+	out.Reset()
+	if count, size, err = processDuplicates(&out, &errOut, duplicates, true, false); err != nil || count != 1 || size != 4 || out.Len() > 0 {
+		t.Errorf("processDuplicates() = %d, %d, %v; output %q", count, size, err, out.String())
+	}
+	if !sameFile(want.Path, want.Original) {
+		t.Error("the duplicate is not hardlinked")
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(want.Path)); len(entries) != 3 {
+		t.Errorf("unexpected files left: %v", entries)
+	}
+
+	if duplicates, err = findDuplicates(data); err != nil || len(duplicates) != 0 {
+		t.Errorf("findDuplicates() after hardlinking = %+v, %v", duplicates, err)
+	}
+}
+
+func TestDuplicatesSummary(t *testing.T) {
+	if got := duplicatesSummary(2, 3<<20, false); !strings.HasSuffix(got, "3.0 MiB reclaimable") {
+		t.Errorf("duplicatesSummary() = %q", got)
+	}
+	if got := duplicatesSummary(2, 1024, true); got != "2 duplicate(s) hardlinked, 1.0 KiB reclaimed" {
+		t.Errorf("duplicatesSummary() = %q", got)
+	}
+}
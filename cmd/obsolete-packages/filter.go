@@ -0,0 +1,75 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var (
+	// packageExtensions - file extensions of FreeBSD packages, both pkg(8) and legacy pkg_tools ones
+	packageExtensions = []string{".pkg", ".txz", ".tzst", ".tbz", ".tgz"}
+
+	// repositoryFiles - names (without extension) of repository metadata that share package extensions
+	repositoryFiles = []string{"meta", "packagesite", "data", "digests", "filesite"}
+)
+
+// isPackageFile reports whether the file name looks like a package rather than repository metadata
+// (meta.conf, packagesite.pkg, data.pkg, digests.pkg, *.sig, *.pub and alike).
+func isPackageFile(name string) bool {
+	name = filepath.Base(name)
+	ext := filepath.Ext(name)
+	if !slices.Contains(packageExtensions, ext) {
+		return false
+	}
+	return !slices.Contains(repositoryFiles, strings.TrimSuffix(name, ext))
+}
+
+// patternList - repeatable command line flag of shell patterns as understood by filepath.Match
+type patternList []string
+
+// String -
+func (p *patternList) String() string {
+	return strings.Join(*p, ",")
+}
+
+// Set validates and appends a pattern.
+func (p *patternList) Set(pattern string) error {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return err
+	}
+	*p = append(*p, pattern)
+	return nil
+}
+
+// Match reports whether any pattern matches the path. Patterns without a path separator
+// are matched against the base name, other ones against the whole path.
+func (p patternList) Match(path string) bool {
+	for _, pattern := range p {
+		name := path
+		if !strings.ContainsRune(pattern, filepath.Separator) {
+			name = filepath.Base(path)
+		}
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// fileFilter - selection of package files by -include and -exclude patterns
+type fileFilter struct {
+	include, exclude patternList
+}
+
+// Accept reports whether the path is a package file matching any include pattern (if there are any)
+// and none of the exclude patterns.
+func (f *fileFilter) Accept(path string) bool {
+	if !isPackageFile(path) {
+		return false
+	}
+	if len(f.include) > 0 && !f.include.Match(path) {
+		return false
+	}
+	return !f.exclude.Match(path)
+}
@@ -0,0 +1,79 @@
+package main
+
+import "testing"
+
+func TestIsPackageFile(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"/repo/All/bash-5.2.37.pkg", true},
+		{"/repo/All/bash-5.2.37.txz", true},
+		{"/repo/All/bash-5.2.37.tzst", true},
+		{"/repo/All/bash-5.2.37.tbz", true},
+		{"/repo/All/bash-5.2.37.tgz", true},
+		{"/repo/Latest/pkg.pkg", true},
+		{"/repo/meta.conf", false},
+		{"/repo/meta", false},
+		{"/repo/meta.txz", false},
+		{"/repo/packagesite.pkg", false},
+		{"/repo/packagesite.txz", false},
+		{"/repo/packagesite.yaml", false},
+		{"/repo/data.pkg", false},
+		{"/repo/digests.pkg", false},
+		{"/repo/filesite.pkg", false},
+		{"/repo/packagesite.pkg.sig", false},
+		{"/repo/repo.pub", false},
+		{"/repo/All/bash-5.2.37", false},
+		{"/repo/All/bash-5.2.37.pkg.tmp", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPackageFile(tt.name); got != tt.want {
+				t.Errorf("isPackageFile(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatternListSet(t *testing.T) {
+	var p patternList
+	if err := p.Set("[a-"); err == nil {
+		t.Errorf("Set() accepted a malformed pattern")
+	}
+	if err := p.Set("py3*"); err != nil {
+		t.Errorf("Set() error = %v", err)
+	}
+	if p.String() != "py3*" {
+		t.Errorf("String() = %q", p.String())
+	}
+}
+
+func TestFileFilterAccept(t *testing.T) {
+	tests := []struct {
+		name             string
+		include, exclude patternList
+		path             string
+		want             bool
+	}{
+		{"no patterns", nil, nil, "/repo/All/bash-5.2.37.pkg", true},
+		{"metadata", nil, nil, "/repo/packagesite.pkg", false},
+		{"included", patternList{"py3*"}, nil, "/repo/All/py311-pip-24.0.pkg", true},
+		{"not included", patternList{"py3*"}, nil, "/repo/All/bash-5.2.37.pkg", false},
+		{"excluded", nil, patternList{"bash-*"}, "/repo/All/bash-5.2.37.pkg", false},
+		{"included and excluded", patternList{"py3*"}, patternList{"*-pip-*"}, "/repo/All/py311-pip-24.0.pkg", false},
+		{"path pattern", nil, patternList{"/repo/Latest/*"}, "/repo/Latest/pkg.pkg", false},
+		{"path pattern mismatch", nil, patternList{"/repo/Latest/*"}, "/repo/All/pkg-1.21.3.pkg", true},
+		{"metadata included", patternList{"*"}, nil, "/repo/data.pkg", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := fileFilter{include: tt.include, exclude: tt.exclude}
+			if got := f.Accept(tt.path); got != tt.want {
+				t.Errorf("Accept(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
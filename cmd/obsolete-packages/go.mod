@@ -2,4 +2,11 @@ module github.com/omilevskyi/go/obsolete-packages
 
 go 1.26
 
-require github.com/omilevskyi/go v0.1.1
+require (
+	github.com/mattn/go-isatty v0.0.24
+	github.com/omilevskyi/go v0.1.1
+)
+
+require golang.org/x/sys v0.47.0 // indirect
+
+replace github.com/omilevskyi/go => ../..
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	ut "github.com/omilevskyi/go/pkg/utils"
+)
+
+const (
+	idxSep    = "|"
+	numFields = 13
+
+	statusMissing  = "missing"
+	statusOutdated = "outdated"
+	statusOrphaned = "orphaned"
+)
+
+// readIndex reads a FreeBSD ports INDEX file and returns its entries keyed by package name.
+// Path of every returned VersionType holds the port origin (e.g. "shells/bash") instead of a file path.
+// 0            1       2            3       4          5          6          7             8        9   10           11         12
+// name-version|portdir|local_prefix|comment|descr_file|maintainer|categories|build_depends|run_deps|www|extract_deps|patch_deps|fetch_deps
+func readIndex(r io.Reader) (map[string]*VersionType, error) {
+	index, lineCount := map[string]*VersionType{}, 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineCount++
+		fields := strings.SplitN(scanner.Text(), idxSep, numFields)
+		if n := len(fields); n < numFields {
+			return nil, fmt.Errorf("line %d: invalid number of fields: %d", lineCount, n)
+		}
+
+		origin := fields[1]
+		if splitted := strings.Split(origin, string(filepath.Separator)); len(splitted) > 1 {
+			origin = filepath.Join(splitted[len(splitted)-2:]...)
+		}
+
+		if k, ver := nameAndVersion(fields[0], origin); k != "" {
+			index[k] = ver
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// writeIndexReport compares INDEX entries against the packages found in the repository
+// and writes tab-separated "status name-version detail" lines sorted by package name:
+// missing - the port has no built package (detail is the port origin);
+// outdated - the newest package is older than INDEX (detail is the package path);
+// orphaned - the package has no INDEX entry (detail is the package path).
+// It returns the number of lines written.
+func writeIndexReport(w io.Writer, index map[string]*VersionType, data map[string]*[]VersionType) (int, error) {
+	keys := ut.Keys(index)
+	for k := range data {
+		if _, ok := index[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	count := 0
+	for _, k := range ut.Arrange(keys) {
+		idx, pkgs := index[k], data[k]
+		var err error
+		switch {
+		case pkgs == nil:
+			_, err = fmt.Fprintf(w, "%s\t%s-%s\t%s\n", statusMissing, k, idx.String(), idx.Path)
+			count++
+		case idx == nil:
+			for _, ver := range *pkgs {
+				if _, err = fmt.Fprintf(w, "%s\t%s-%s\t%s\n", statusOrphaned, k, ver.String(), ver.Path); err != nil {
+					break
+				}
+				count++
+			}
+		default:
+			slices.SortFunc(*pkgs, compareVersionDesc)
+			if newest := (*pkgs)[0]; compareVersionDesc(newest, *idx) > 0 {
+				_, err = fmt.Fprintf(w, "%s\t%s-%s\t%s\n", statusOutdated, k, idx.String(), newest.Path)
+				count++
+			}
+		}
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const testIndex = `bash-5.2.37|/usr/ports/shells/bash|/usr/local|The GNU Project's Bourne Again SHell|/usr/ports/shells/bash/pkg-descr|ports@FreeBSD.org|shells||||||
+curl-8.11.1_1|/usr/ports/ftp/curl|/usr/local|Command line tool and library for transferring data with URLs|/usr/ports/ftp/curl/pkg-descr|ports@FreeBSD.org|ftp net www||||||
+git-2.47.1|/usr/ports/devel/git|/usr/local|Distributed source code management tool|/usr/ports/devel/git/pkg-descr|ports@FreeBSD.org|devel||||||
+pkg-1.21.3,1|/usr/ports/ports-mgmt/pkg|/usr/local|Package manager|/usr/ports/ports-mgmt/pkg/pkg-descr|ports@FreeBSD.org|ports-mgmt||||||
+`
+
+func TestReadIndex(t *testing.T) {
+	index, err := readIndex(strings.NewReader(testIndex))
+	if err != nil {
+		t.Fatalf("readIndex() error = %v", err)
+	}
+
+	if len(index) != 4 {
+		t.Fatalf("readIndex() returned %d entries, want 4", len(index))
+	}
+
+	tests := []struct {
+		key, origin, version string
+	}{
+		{"bash", "shells/bash", "5.2.37"},
+		{"curl", "ftp/curl", "8.11.1_1"},
+		{"git", "devel/git", "2.47.1"},
+		{"pkg", "ports-mgmt/pkg", "1.21.3,1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			ver, ok := index[tt.key]
+			if !ok {
+				t.Fatalf("readIndex() has no %q entry", tt.key)
+			}
+			if ver.Path != tt.origin {
+				t.Errorf("origin = %q, want %q", ver.Path, tt.origin)
+			}
+			if got := ver.String(); got != tt.version {
+				t.Errorf("String() = %q, want %q", got, tt.version)
+			}
+		})
+	}
+}
+
+func TestReadIndexInvalid(t *testing.T) {
+	if _, err := readIndex(strings.NewReader("bash-5.2.37|/usr/ports/shells/bash\n")); err == nil {
+		t.Fatalf("readIndex() expected error for short line")
+	}
+}
+
+func TestWriteIndexReport(t *testing.T) {
+	index, err := readIndex(strings.NewReader(testIndex))
+	if err != nil {
+		t.Fatalf("readIndex() error = %v", err)
+	}
+
+	data := map[string]*[]VersionType{}
+	for _, path := range []string{
+		"/repo/All/bash-5.2.36.pkg",
+		"/repo/All/bash-5.2.37.pkg",
+		"/repo/All/curl-8.11.1.pkg",
+		"/repo/All/pkg-1.21.3,1.pkg",
+		"/repo/All/zsh-5.9_5.pkg",
+	} {
+		k, ver := keyAndVersion(path)
+		if versions, ok := data[k]; ok {
+			*versions = append(*versions, *ver)
+		} else {
+			data[k] = &[]VersionType{*ver}
+		}
+	}
+
+	var buf bytes.Buffer
+	count, err := writeIndexReport(&buf, index, data)
+	if err != nil {
+		t.Fatalf("writeIndexReport() error = %v", err)
+	}
+
+	want := "outdated\tcurl-8.11.1_1\t/repo/All/curl-8.11.1.pkg\n" +
+		"missing\tgit-2.47.1\tdevel/git\n" +
+		"orphaned\tzsh-5.9_5\t/repo/All/zsh-5.9_5.pkg\n"
+
+	if got := buf.String(); got != want {
+		t.Errorf("writeIndexReport() =\n%s\nwant\n%s", got, want)
+	}
+
+	if count != 3 {
+		t.Errorf("writeIndexReport() count = %d, want 3", count)
+	}
+}
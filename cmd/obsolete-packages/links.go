@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// PackageLink - symbolic link to a package file, e.g. Latest/pkg.pkg -> ../All/pkg-1.21.3.pkg
+type PackageLink struct {
+	Path   string
+	Target string // absolute path the link points to
+}
+
+// readPackageLink reads a symbolic link; its target does not have to exist.
+func readPackageLink(path string) (PackageLink, error) {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return PackageLink{}, err
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+	target, err = filepath.Abs(target)
+	return PackageLink{Path: path, Target: target}, err
+}
+
+// repointLink atomically replaces the symbolic link so that it points to target,
+// relatively if the original link was relative.
+func repointLink(path, target string) error {
+	old, err := os.Readlink(path)
+	if err != nil {
+		return err
+	}
+
+	if !filepath.IsAbs(old) {
+		dir, err := filepath.Abs(filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		if target, err = filepath.Rel(dir, target); err != nil {
+			return err
+		}
+	}
+
+	tmp := path + ".tmp" + strconv.Itoa(os.Getpid())
+	if err = os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// processLinks handles links to packages once obsolete ones are gone (apply is true) or are about to go.
+// superseded maps absolute paths of obsolete packages to absolute paths of the versions kept instead.
+// Links to obsolete packages are reported to errw unless fix is true and apply is true: then such links
+// are repointed to the kept versions, and links whose targets do not exist are removed.
+// Changed links are written to w when verbose. It returns the number of links changed.
+func processLinks(w, errw io.Writer, links []PackageLink, superseded map[string]string, apply, fix, verbose bool) (int, error) {
+	count, errs := 0, []error(nil)
+	for _, link := range links {
+		replacement, obsolete := superseded[link.Target]
+
+		if !apply {
+			if obsolete {
+				_, _ = fmt.Fprintf(errw, "Link to obsolete package: %s -> %s\n", link.Path, link.Target)
+			}
+			continue
+		}
+
+		if _, err := os.Stat(link.Target); err == nil {
+			continue // the target is still there
+		}
+
+		if !fix {
+			if obsolete {
+				_, _ = fmt.Fprintf(errw, "Dangling link: %s -> %s\n", link.Path, link.Target)
+			}
+			continue
+		}
+
+		var err error
+		if obsolete {
+			if err = repointLink(link.Path, replacement); err == nil && verbose {
+				_, _ = fmt.Fprintln(w, link.Path, "->", replacement)
+			}
+		} else if err = os.Remove(link.Path); err == nil && verbose {
+			_, _ = fmt.Fprintln(w, link.Path)
+		}
+
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			count++
+		}
+	}
+	return count, errors.Join(errs...)
+}
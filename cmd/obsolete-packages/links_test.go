@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// makeLinkedRepo creates All/pkg-1.0.pkg, All/pkg-1.1.pkg, All/curl-8.0.pkg (removed later)
+// and links Latest/pkg.pkg -> ../All/pkg-1.0.pkg, Latest/curl.pkg -> ../All/curl-8.0.pkg.
+func makeLinkedRepo(t *testing.T) (string, []PackageLink) {
+	t.Helper()
+	repo, err := filepath.Abs(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, repo, "All/pkg-1.0.pkg", "All/pkg-1.1.pkg", "All/curl-8.0.pkg")
+	if err = os.Mkdir(filepath.Join(repo, "Latest"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	var links []PackageLink
+	for name, target := range map[string]string{"pkg.pkg": "../All/pkg-1.0.pkg", "curl.pkg": "../All/curl-8.0.pkg"} {
+		path := filepath.Join(repo, "Latest", name)
+		if err = os.Symlink(target, path); err != nil {
+			t.Fatal(err)
+		}
+		link, err := readPackageLink(path)
+		if err != nil {
+			t.Fatalf("readPackageLink() error = %v", err)
+		}
+		links = append(links, link)
+	}
+	return repo, links
+}
+
+func TestReadPackageLink(t *testing.T) {
+	repo, _ := makeLinkedRepo(t)
+	link, err := readPackageLink(filepath.Join(repo, "Latest", "pkg.pkg"))
+	if err != nil {
+		t.Fatalf("readPackageLink() error = %v", err)
+	}
+	if want := filepath.Join(repo, "All", "pkg-1.0.pkg"); link.Target != want {
+		t.Errorf("Target = %q, want %q", link.Target, want)
+	}
+}
+
+func TestProcessLinksReport(t *testing.T) {
+	repo, links := makeLinkedRepo(t)
+	superseded := map[string]string{
+		filepath.Join(repo, "All", "pkg-1.0.pkg"): filepath.Join(repo, "All", "pkg-1.1.pkg"),
+	}
+
+	var out, errOut bytes.Buffer
+	count, err := processLinks(&out, &errOut, links, superseded, false, true, true)
+	if err != nil || count != 0 {
+		t.Fatalf("processLinks() = %d, %v", count, err)
+	}
+	if !strings.Contains(errOut.String(), "Link to obsolete package: "+filepath.Join(repo, "Latest", "pkg.pkg")) {
+		t.Errorf("unexpected report: %q", errOut.String())
+	}
+	if strings.Contains(errOut.String(), "curl") || out.Len() > 0 {
+		t.Errorf("unexpected output: %q, %q", out.String(), errOut.String())
+	}
+}
+
+func TestProcessLinksFix(t *testing.T) {
+	repo, links := makeLinkedRepo(t)
+	obsolete := filepath.Join(repo, "All", "pkg-1.0.pkg")
+	superseded := map[string]string{obsolete: filepath.Join(repo, "All", "pkg-1.1.pkg")}
+	for _, path := range []string{obsolete, filepath.Join(repo, "All", "curl-8.0.pkg")} {
+		if err := os.Remove(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var errOut bytes.Buffer
+	count, err := processLinks(&bytes.Buffer{}, &errOut, links, superseded, true, false, false)
+	if err != nil || count != 0 {
+		t.Fatalf("processLinks() without fix = %d, %v", count, err)
+	}
+	if !strings.Contains(errOut.String(), "Dangling link: "+filepath.Join(repo, "Latest", "pkg.pkg")) {
+		t.Errorf("unexpected report: %q", errOut.String())
+	}
+
+	count, err = processLinks(&bytes.Buffer{}, &bytes.Buffer{}, links, superseded, true, true, false)
+	if err != nil || count != 2 {
+		t.Fatalf("processLinks() = %d, %v", count, err)
+	}
+
+	if target, err := os.Readlink(filepath.Join(repo, "Latest", "pkg.pkg")); err != nil || target != filepath.Join("..", "All", "pkg-1.1.pkg") {
+		t.Errorf("repointed link = %q, %v", target, err)
+	}
+	if _, err = os.Lstat(filepath.Join(repo, "Latest", "curl.pkg")); !os.IsNotExist(err) {
+		t.Errorf("dangling link was not removed: %v", err)
+	}
+}
@@ -3,13 +3,18 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"slices"
+	"time"
 
+	"github.com/mattn/go-isatty"
 	ut "github.com/omilevskyi/go/pkg/utils"
 )
 
@@ -54,16 +59,43 @@ func readStdout(cmdPath string, args ...string) (string, error) {
 }
 
 func main() {
-	var helpFlag, verboseFlag, versionFlag, deleteFlag bool
+	var helpFlag, verboseFlag, versionFlag, deleteFlag, globalFlag, fixLinksFlag, dedupFlag, hardlinkFlag, scriptFlag, interactiveFlag bool
+	var indexFile, moveTo, restoreFile, format, auditLogFile string
+	var keepCount, keepDays, purgeDays, keepSnapshots, jobs int
+	var filter fileFilter
+	protection := Protection{}
 
 	flag.BoolVar(&helpFlag, "help", false, "Display help message")
 	flag.BoolVar(&versionFlag, "version", false, "Show version information")
 	flag.BoolVar(&verboseFlag, "verbose", false, "Enable verbose output")
 	flag.BoolVar(&deleteFlag, "delete", false, "Delete obsolete packages")
+	flag.BoolVar(&interactiveFlag, "interactive", false, "Review the versions of every package on the terminal before anything is removed")
+	flag.BoolVar(&scriptFlag, "script", false, "Print a shell script removing obsolete packages instead of the list")
+	flag.StringVar(&auditLogFile, "audit-log", "", "Append packages removed by -delete to the audit log file")
+	flag.BoolVar(&fixLinksFlag, "fix-links", false, "Repoint links to removed packages to the kept versions and remove dangling links")
+	flag.StringVar(&moveTo, "move-to", "", "Move obsolete packages into the quarantine directory")
+	flag.StringVar(&restoreFile, "restore", "", "Move packages listed in the quarantine manifest back")
+	flag.IntVar(&purgeDays, "purge-days", 0, "Purge packages quarantined more than the given number of days ago")
+	flag.IntVar(&keepCount, "keep", 1, "Number of the newest versions to keep per package")
+	flag.IntVar(&keepDays, "keep-days", 0, "Keep versions modified within the given number of days")
+	flag.IntVar(&keepSnapshots, "keep-snapshots", 0, "Prune poudriere .real_* snapshots except the given number of the newest ones; directories without them are reported")
+	flag.Func("pin", "Never remove the package name or name-version (repeatable)", func(entry string) error {
+		protection.Add(entry)
+		return nil
+	})
+	flag.Func("protect", "Never remove name-version entries listed in the file, e.g. by \"pkg query %n-%v\" (repeatable)", protection.ReadFile)
+	flag.BoolVar(&globalFlag, "global", false, "Group packages by name across all repositories")
+	flag.Var(&filter.include, "include", "Consider only package files matching the pattern (repeatable)")
+	flag.Var(&filter.exclude, "exclude", "Ignore package files matching the pattern (repeatable)")
+	flag.StringVar(&format, "format", "", "Print a report of kept and obsolete versions: json, csv or table")
+	flag.IntVar(&jobs, "jobs", runtime.GOMAXPROCS(0), "Number of directories scanned concurrently")
+	flag.BoolVar(&dedupFlag, "dedup", false, "Report byte-identical package files found in several directories instead of obsolete ones")
+	flag.BoolVar(&hardlinkFlag, "hardlink", false, "Replace duplicates found by -dedup with hard links")
+	flag.StringVar(&indexFile, "index", "", "Compare packages against the ports INDEX file instead of looking for obsolete ones")
 	flag.Parse()
 
 	if helpFlag {
-		fmt.Fprintln(os.Stderr, "Usage: "+appName+" [-help] [-version] [-verbose] [-delete] [packages_directories]")
+		fmt.Fprintln(os.Stderr, "Usage: "+appName+" [-help] [-version] [-verbose] [-delete [-audit-log FILE] | -move-to DIR [-purge-days N] | -script] [-fix-links] [-restore MANIFEST] [-interactive] [-keep N] [-keep-days D] [-include PATTERN] [-exclude PATTERN] [-pin NAME] [-protect FILE] [-global] [-jobs N] [-keep-snapshots N] [-format json|csv|table] [-dedup [-hardlink]] [-index INDEX] [packages_directories]")
 		os.Exit(0)
 	}
 
@@ -72,7 +104,76 @@ func main() {
 		os.Exit(0)
 	}
 
-	args, data, err := flag.Args(), map[string]*[]VersionType{}, error(nil)
+	if keepCount < 1 {
+		fmt.Fprintln(os.Stderr, "-keep must be at least 1")
+		os.Exit(2)
+	}
+
+	if hardlinkFlag && !dedupFlag {
+		fmt.Fprintln(os.Stderr, "-hardlink requires -dedup")
+		os.Exit(2)
+	}
+
+	if jobs < 1 {
+		fmt.Fprintln(os.Stderr, "-jobs must be at least 1")
+		os.Exit(2)
+	}
+
+	if deleteFlag && moveTo != "" {
+		fmt.Fprintln(os.Stderr, "-delete and -move-to are mutually exclusive")
+		os.Exit(2)
+	}
+
+	if scriptFlag && (deleteFlag || moveTo != "" || format != "") {
+		fmt.Fprintln(os.Stderr, "-script cannot be used with -delete, -move-to or -format")
+		os.Exit(2)
+	}
+
+	if interactiveFlag && !isatty.IsTerminal(os.Stdin.Fd()) && !isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+		fmt.Fprintln(os.Stderr, "-interactive requires standard input to be a terminal")
+		os.Exit(2)
+	}
+
+	if keepSnapshots > 0 && moveTo != "" {
+		fmt.Fprintln(os.Stderr, "-keep-snapshots cannot be used with -move-to")
+		os.Exit(2)
+	}
+
+	if auditLogFile != "" && !deleteFlag {
+		fmt.Fprintln(os.Stderr, "-audit-log requires -delete")
+		os.Exit(2)
+	}
+
+	if purgeDays != 0 && moveTo == "" {
+		fmt.Fprintln(os.Stderr, "-purge-days requires -move-to")
+		os.Exit(2)
+	}
+
+	if format != "" && !slices.Contains(reportFormats, format) {
+		fmt.Fprintln(os.Stderr, "-format must be one of:", reportFormats)
+		os.Exit(2)
+	}
+
+	if restoreFile != "" {
+		count, err := restoreManifest(restoreFile, os.Stdout)
+		if verboseFlag {
+			fmt.Fprintf(os.Stderr, "%d package(s) restored\n", count)
+		}
+		ut.IsErr(err, 207, "restoreManifest()")
+		return
+	}
+
+	if moveTo != "" && purgeDays > 0 {
+		purged, err := purgeQuarantine(moveTo, time.Now().AddDate(0, 0, -purgeDays))
+		ut.IsErr(err, -1, "purgeQuarantine()")
+		if verboseFlag {
+			for _, batch := range purged {
+				fmt.Fprintln(os.Stderr, "Purged:", batch)
+			}
+		}
+	}
+
+	args, err := flag.Args(), error(nil)
 	if len(args) < 1 {
 		rootDir, err := ut.RootDirectory()
 		ut.IsErr(err, 201, "ut.RootDirectory()")
@@ -86,46 +187,239 @@ func main() {
 		args = []string{packagesDir}
 	}
 
-	// Recursively walks through each directory provided in args
-	for i := 0; i < len(args); i++ {
-		err = filepath.Walk(args[i], func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if info.Mode().IsRegular() {
-				if k, ver := keyAndVersion(path); k != "" {
-					if versions, ok := data[k]; ok {
-						if !versionsContain(*versions, ver.Path) {
-							*versions = append(*versions, *ver)
-						}
-					} else {
-						data[k] = &[]VersionType{*ver}
-					}
-				} else if verboseFlag {
-					fmt.Fprintln(os.Stderr, "Empty key:", path)
-				}
-			}
-			return nil
-		})
-		if err != nil {
-			fmt.Fprintln(os.Stderr, "ERROR:", err)
+	quarantineDir := ""
+	if moveTo != "" {
+		quarantineDir, err = filepath.Abs(moveTo)
+		ut.IsErr(err, 209, "filepath.Abs()")
+	}
+
+	// INDEX has a single version per port, so the comparison is always global
+	var finder *RepositoryFinder
+	if !globalFlag && indexFile == "" {
+		finder = NewRepositoryFinder()
+	}
+
+	poudriere, catalogues := NewPoudriereDirs(), NewCatalogues()
+	catalogues.Invalid = func(path string, err error) {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+	}
+	scanner := &Scanner{Jobs: jobs, Filter: &filter, Finder: finder, Poudriere: poudriere, Catalogues: catalogues, QuarantineDir: quarantineDir}
+	if verboseFlag {
+		scanner.Log = os.Stderr
+	}
+	data, links := scanner.Scan(args)
+
+	for _, missing := range catalogues.Missing() {
+		fmt.Fprintln(os.Stderr, "Missing catalogued package:", missing)
+	}
+
+	if dedupFlag {
+		duplicates, err := findDuplicates(data)
+		ut.IsErr(err, -1, "findDuplicates()")
+
+		count, size, err := processDuplicates(os.Stdout, os.Stderr, duplicates, hardlinkFlag, verboseFlag)
+		ut.IsErr(err, -1, "processDuplicates()")
+
+		if verboseFlag {
+			fmt.Fprintln(os.Stderr, duplicatesSummary(count, size, hardlinkFlag))
+		}
+		return
+	}
+
+	if indexFile != "" {
+		file, err := os.Open(indexFile)
+		ut.IsErr(err, 204, "os.Open()")
+
+		index, err := readIndex(file)
+		_ = file.Close()
+		ut.IsErr(err, 205, "readIndex()")
+
+		count, err := writeIndexReport(os.Stdout, index, data)
+		ut.IsErr(err, 206, "writeIndexReport()")
+
+		if verboseFlag {
+			fmt.Fprintf(os.Stderr, "%d INDEX entries, %d package names, %d findings\n", len(index), len(data), count)
+		}
+		return
+	}
+
+	configs, err := protection.ReadConfig(ut.ConfigDirectories())
+	ut.IsErr(err, 210, "protection.ReadConfig()")
+	if verboseFlag {
+		for _, path := range configs {
+			fmt.Fprintln(os.Stderr, "Protection list:", path)
 		}
 	}
 
+	keepAfter := time.Time{}
+	if keepDays > 0 {
+		keepAfter = time.Now().AddDate(0, 0, -keepDays)
+	}
+
+	var q *Quarantine
+	if moveTo != "" {
+		q, err = NewQuarantine(moveTo, time.Now())
+		ut.IsErr(err, 208, "NewQuarantine()")
+		defer func() {
+			ut.IsErr(q.Close(), -1, "q.Close()")
+		}()
+	}
+
+	// Paths go to stdout unless a report is requested, then they are only shown with -verbose
+	out, listPaths := io.Writer(os.Stdout), !deleteFlag && q == nil
+	if format != "" || scriptFlag {
+		out, listPaths = os.Stderr, false
+	}
+
+	var script *Script
+	if scriptFlag {
+		script, err = NewScript(os.Stdout, time.Now())
+		ut.IsErr(err, 212, "NewScript()")
+		defer func() {
+			ut.IsErr(script.Close(), -1, "script.Close()")
+		}()
+	}
+
+	var audit *AuditLog
+	if auditLogFile != "" {
+		audit, err = OpenAuditLog(auditLogFile)
+		ut.IsErr(err, 213, "OpenAuditLog()")
+		defer func() {
+			ut.IsErr(audit.Close(), -1, "audit.Close()")
+		}()
+	}
+
+	var reviewer *bufio.Reader
+	if interactiveFlag {
+		reviewer = bufio.NewReader(os.Stdin)
+	}
+
+	superseded := map[string]string{} // absolute paths of obsolete packages -> the newest kept versions
+	var reports []GroupReport
+
 	// Iterates over all version groups sorted by key, and does the job aimed for.
 	for _, k := range ut.Arrange(ut.Keys(data)) {
 		versions := *data[k]
-		if vlen := len(versions); vlen > 1 {
+		if len(versions) > keepCount || reviewer != nil && len(versions) > 1 {
 			slices.SortFunc(versions, compareVersionDesc)
-			for i := 1; i < vlen; i++ {
-				if path := versions[i].Path; deleteFlag {
+			protected := func(ver VersionType) bool { return protection.Protects(filepath.Base(k), ver) }
+			obsolete := obsoleteVersions(versions, keepCount, keepAfter, protected)
+			if reviewer != nil {
+				if obsolete, err = reviewVersions(reviewer, os.Stderr, k, versions, obsolete, protected); errors.Is(err, errQuit) {
+					break
+				}
+				ut.IsErr(err, 215, "reviewVersions()")
+			}
+			if len(obsolete) == 0 {
+				continue
+			}
+
+			newestPath, newest := "", ""
+			for _, ver := range versions {
+				if !versionsContain(obsolete, ver.Path) {
+					newestPath = ver.Path
+					newest, _ = filepath.Abs(newestPath)
+					break
+				}
+			}
+
+			report := GroupReport{Key: k, Name: filepath.Base(k)}
+			for _, ver := range versions {
+				if !versionsContain(obsolete, ver.Path) {
+					report.Kept = append(report.Kept, FileReport{Path: ver.Path, Version: ver.String(), Size: ver.Size, Origin: ver.Origin})
+				}
+			}
+
+			for _, ver := range obsolete {
+				abs, err := filepath.Abs(ver.Path)
+				if err == nil && newest != "" {
+					superseded[abs] = newest
+				}
+				file := FileReport{Path: ver.Path, Version: ver.String(), Size: ver.Size, Origin: ver.Origin}
+				switch path := ver.Path; {
+				case q != nil:
+					if dst, err := q.Move(ver.Root, path); err != nil {
+						fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+					} else {
+						file.Removed = true
+						if verboseFlag {
+							fmt.Fprintln(out, path, "->", dst)
+						}
+					}
+				case deleteFlag:
 					if err = os.Remove(path); err != nil {
 						fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
-					} else if verboseFlag {
-						fmt.Println(path)
+					} else {
+						file.Removed = true
+						if verboseFlag {
+							fmt.Fprintln(out, path)
+						}
+						if audit != nil {
+							ut.IsErr(audit.Record(abs, ver.Size, newest), -1, "audit.Record()")
+						}
+					}
+				case script != nil:
+					ut.IsErr(script.Remove(filepath.Base(k), path, newestPath), 214, "script.Remove()")
+					if verboseFlag {
+						fmt.Fprintln(out, path)
+					}
+				case listPaths || verboseFlag:
+					fmt.Fprintln(out, path)
+				}
+				report.Obsolete = append(report.Obsolete, file)
+			}
+			reports = append(reports, report)
+		}
+	}
+
+	if format != "" {
+		ut.IsErr(writeReport(os.Stdout, format, reports), 211, "writeReport()")
+	}
+	if format != "" || verboseFlag {
+		fmt.Fprintln(os.Stderr, summarize(reports))
+	}
+
+	_, err = processLinks(out, os.Stderr, links, superseded, deleteFlag || q != nil, fixLinksFlag, verboseFlag)
+	ut.IsErr(err, -1, "processLinks()")
+
+	if keepSnapshots > 0 {
+		dirs := poudriere.Dirs()
+		if len(dirs) == 0 {
+			fmt.Fprintln(os.Stderr, "-keep-snapshots: no poudriere package directories found")
+		}
+		for _, dir := range dirs {
+			stale, err := staleSnapshots(dir, poudriere.Live(dir), keepSnapshots)
+			if errors.Is(err, errNoSnapshots) {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", dir, err)
+				continue
+			}
+			if ut.IsErr(err, -1, "staleSnapshots()") {
+				continue
+			}
+			for _, snapshot := range stale {
+				size, protected, err := inspectSnapshot(snapshot, protection)
+				if ut.IsErr(err, -1, "inspectSnapshot()") {
+					continue
+				}
+				if protected {
+					fmt.Fprintf(os.Stderr, "%s: kept, it contains protected packages\n", snapshot)
+					continue
+				}
+				if deleteFlag {
+					if err = os.RemoveAll(snapshot); err != nil {
+						fmt.Fprintf(os.Stderr, "%s: %v\n", snapshot, err)
+						continue
+					}
+					if audit != nil {
+						ut.IsErr(audit.Record(snapshot, size, poudriere.Live(dir)), -1, "audit.Record()")
+					}
+					if verboseFlag {
+						fmt.Fprintln(out, snapshot)
 					}
+				} else if script != nil {
+					ut.IsErr(script.RemoveSnapshot(snapshot), 214, "script.RemoveSnapshot()")
 				} else {
-					fmt.Println(path)
+					fmt.Fprintln(out, snapshot)
 				}
 			}
 		}
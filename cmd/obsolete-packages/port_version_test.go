@@ -3,99 +3,11 @@ package main
 import (
 	"path/filepath"
 	"reflect"
+	"slices"
 	"testing"
-)
-
-func TestIntSuffix(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    string
-		sep      byte
-		wantInt  int
-		wantRest string
-	}{
-		{
-			name:     "Standard comma suffix",
-			input:    "pkg-1.2.3_4,5",
-			sep:      ',',
-			wantInt:  5,
-			wantRest: "pkg-1.2.3_4",
-		},
-		{
-			name:     "Standard underscore suffix",
-			input:    "pkg-1.2.3_4",
-			sep:      '_',
-			wantInt:  4,
-			wantRest: "pkg-1.2.3",
-		},
-		{
-			name:     "No separator present",
-			input:    "pkg-1.2.3",
-			sep:      ',',
-			wantInt:  0,
-			wantRest: "pkg-1.2.3",
-		},
-		{
-			name:     "Separator at end with no number",
-			input:    "pkg-1.2.3_,",
-			sep:      ',',
-			wantInt:  0,
-			wantRest: "pkg-1.2.3_",
-		},
-		{
-			name:     "Non-numeric suffix",
-			input:    "pkg-1.2.3_abc",
-			sep:      '_',
-			wantInt:  0,
-			wantRest: "pkg-1.2.3",
-		},
-		{
-			name:     "Multiple separators, only last matters",
-			input:    "pkg-1.2.3_4_5",
-			sep:      '_',
-			wantInt:  5,
-			wantRest: "pkg-1.2.3_4",
-		},
-		{
-			name:     "Separator at beginning",
-			input:    "_5",
-			sep:      '_',
-			wantInt:  5,
-			wantRest: "",
-		},
-		{
-			name:     "Empty string",
-			input:    "",
-			sep:      ',',
-			wantInt:  0,
-			wantRest: "",
-		},
-		{
-			name:     "Only separator",
-			input:    ",",
-			sep:      ',',
-			wantInt:  0,
-			wantRest: "",
-		},
-		{
-			name:     "Separator with negative number (invalid)",
-			input:    "pkg-1.2.3_-5",
-			sep:      '_',
-			wantInt:  0, // strconv.Atoi("-5") is valid, but if you want to reject negatives, this would need logic
-			wantRest: "pkg-1.2.3",
-		},
-	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			gotInt, gotRest := intSuffix(tt.input, tt.sep)
-			if gotInt != tt.wantInt || gotRest != tt.wantRest {
-				t.Errorf("intSuffix(%q, %q) = (%d, %q); want (%d, %q)",
-					tt.input, tt.sep, gotInt, gotRest, tt.wantInt, tt.wantRest)
-			}
-		})
-	}
-}
+	"github.com/omilevskyi/go/pkg/portver"
+)
 
 func TestKeyAndVersion(t *testing.T) {
 	tests := []struct {
@@ -109,10 +21,8 @@ func TestKeyAndVersion(t *testing.T) {
 			input:       "/usr/ports/pkg-name-1.2.3_4,5.pkg",
 			expectedKey: "pkg-name",
 			expected: &VersionType{
-				Path:         "/usr/ports/pkg-name-1.2.3_4,5.pkg",
-				PortVersion:  []string{"1", "2", "3"},
-				PortRevision: 4,
-				PortEpoch:    5,
+				Path:    "/usr/ports/pkg-name-1.2.3_4,5.pkg",
+				Version: portver.New("1.2.3", 4, 5),
 			},
 		},
 		{
@@ -120,10 +30,8 @@ func TestKeyAndVersion(t *testing.T) {
 			input:       "pkg-1.2.3_,7.txz",
 			expectedKey: "pkg",
 			expected: &VersionType{
-				Path:         "pkg-1.2.3_,7.txz",
-				PortVersion:  []string{"1", "2", "3"},
-				PortRevision: 0,
-				PortEpoch:    7,
+				Path:    "pkg-1.2.3_,7.txz",
+				Version: portver.New("1.2.3", 0, 7),
 			},
 		},
 		{
@@ -131,10 +39,8 @@ func TestKeyAndVersion(t *testing.T) {
 			input:       "pkg-1.2.3_4,.txz",
 			expectedKey: "pkg",
 			expected: &VersionType{
-				Path:         "pkg-1.2.3_4,.txz",
-				PortVersion:  []string{"1", "2", "3"},
-				PortRevision: 4,
-				PortEpoch:    0,
+				Path:    "pkg-1.2.3_4,.txz",
+				Version: portver.New("1.2.3", 4, 0),
 			},
 		},
 		{
@@ -142,10 +48,8 @@ func TestKeyAndVersion(t *testing.T) {
 			input:       "pkg-1.2.3.txz",
 			expectedKey: "pkg",
 			expected: &VersionType{
-				Path:         "pkg-1.2.3.txz",
-				PortVersion:  []string{"1", "2", "3"},
-				PortRevision: 0,
-				PortEpoch:    0,
+				Path:    "pkg-1.2.3.txz",
+				Version: portver.New("1.2.3", 0, 0),
 			},
 		},
 		{
@@ -153,10 +57,8 @@ func TestKeyAndVersion(t *testing.T) {
 			input:       "pkg-10.20.30.40_99,88.pkg",
 			expectedKey: "pkg",
 			expected: &VersionType{
-				Path:         "pkg-10.20.30.40_99,88.pkg",
-				PortVersion:  []string{"10", "20", "30", "40"},
-				PortRevision: 99,
-				PortEpoch:    88,
+				Path:    "pkg-10.20.30.40_99,88.pkg",
+				Version: portver.New("10.20.30.40", 99, 88),
 			},
 		},
 		{
@@ -164,10 +66,8 @@ func TestKeyAndVersion(t *testing.T) {
 			input:       "pkg-1_1,1.pkg",
 			expectedKey: "pkg",
 			expected: &VersionType{
-				Path:         "pkg-1_1,1.pkg",
-				PortVersion:  []string{"1"},
-				PortRevision: 1,
-				PortEpoch:    1,
+				Path:    "pkg-1_1,1.pkg",
+				Version: portver.New("1", 1, 1),
 			},
 		},
 		{
@@ -193,10 +93,8 @@ func TestKeyAndVersion(t *testing.T) {
 			input:       "pkg-1.2a.3_4,5.pkg",
 			expectedKey: "pkg",
 			expected: &VersionType{
-				Path:         "pkg-1.2a.3_4,5.pkg",
-				PortVersion:  []string{"1", "2a", "3"},
-				PortRevision: 4,
-				PortEpoch:    5,
+				Path:    "pkg-1.2a.3_4,5.pkg",
+				Version: portver.New("1.2a.3", 4, 5),
 			},
 		},
 		{
@@ -204,10 +102,8 @@ func TestKeyAndVersion(t *testing.T) {
 			input:       "pkg-1.2.3_4,5",
 			expectedKey: "pkg",
 			expected: &VersionType{
-				Path:         "pkg-1.2.3_4,5",
-				PortVersion:  []string{"1", "2", "3"},
-				PortRevision: 4,
-				PortEpoch:    5,
+				Path:    "pkg-1.2.3_4,5",
+				Version: portver.New("1.2.3", 4, 5),
 			},
 		},
 		{
@@ -215,10 +111,8 @@ func TestKeyAndVersion(t *testing.T) {
 			input:       "/usr/../usr/ports/pkg-1.2.3_4,5.pkg",
 			expectedKey: "pkg",
 			expected: &VersionType{
-				Path:         filepath.Clean("/usr/../usr/ports/pkg-1.2.3_4,5.pkg"),
-				PortVersion:  []string{"1", "2", "3"},
-				PortRevision: 4,
-				PortEpoch:    5,
+				Path:    filepath.Clean("/usr/../usr/ports/pkg-1.2.3_4,5.pkg"),
+				Version: portver.New("1.2.3", 4, 5),
 			},
 		},
 		{
@@ -226,10 +120,8 @@ func TestKeyAndVersion(t *testing.T) {
 			input:       "pkg-1.2.3.123",
 			expectedKey: "pkg",
 			expected: &VersionType{
-				Path:         "pkg-1.2.3.123",
-				PortVersion:  []string{"1", "2", "3", "123"},
-				PortRevision: 0,
-				PortEpoch:    0,
+				Path:    "pkg-1.2.3.123",
+				Version: portver.New("1.2.3.123", 0, 0),
 			},
 		},
 	}
@@ -248,3 +140,40 @@ func TestKeyAndVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestCompareVersionDesc(t *testing.T) {
+	want := []string{ // newest first
+		"pkg-2.0,1.pkg",
+		"pkg-1.0b_1.pkg",
+		"pkg-1.0b.pkg",
+		"pkg-1.0a2.pkg",
+		"pkg-1.0a.pkg", // a letter right after a number is newer than the next component
+		"pkg-1.0.1.pkg",
+		"pkg-1.0_2.pkg",
+		"pkg-1.0_1.pkg",
+		"pkg-1.0.pkg",
+		"pkg-1.0rc2.pkg",
+		"pkg-1.0rc1.pkg",
+		"pkg-1.0beta1.pkg",
+		"pkg-1.0alpha2.pkg",
+		"pkg-1.0alpha1.pkg",
+		"pkg-1.0.a.pkg",
+		"pkg-0.31.pkg",
+		"pkg-0.29.pkg",
+		"pkg-0.9.pkg",
+	}
+
+	versions := make([]VersionType, 0, len(want))
+	for i := len(want) - 1; i >= 0; i-- {
+		_, ver := keyAndVersion(want[i])
+		versions = append(versions, *ver)
+	}
+
+	slices.SortFunc(versions, compareVersionDesc)
+
+	for i := range versions {
+		if versions[i].Path != want[i] {
+			t.Errorf("position %d = %q; want %q", i, versions[i].Path, want[i])
+		}
+	}
+}
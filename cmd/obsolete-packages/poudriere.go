@@ -0,0 +1,210 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	ut "github.com/omilevskyi/go/pkg/utils"
+)
+
+// Poudriere keeps every build of data/packages/<jail>-<tree>/ in a .real_<timestamp> snapshot
+// directory, points the .latest symlink at the live one, and builds the next one in .building.
+const (
+	poudriereLatest   = ".latest"
+	poudriereBuilding = ".building"
+	poudriereSnapshot = ".real_"
+)
+
+// isSnapshotName reports whether a directory name is a poudriere snapshot or a snapshot being built.
+func isSnapshotName(name string) bool {
+	return strings.HasPrefix(name, poudriereSnapshot) || name == poudriereBuilding
+}
+
+// PoudriereDirs keeps track of poudriere package directories and their live snapshots;
+// it is safe for concurrent use.
+type PoudriereDirs struct {
+	mu   sync.Mutex
+	live map[string]string // directory -> absolute path of the live snapshot, or ""
+}
+
+// NewPoudriereDirs -
+func NewPoudriereDirs() *PoudriereDirs {
+	return &PoudriereDirs{live: map[string]string{}}
+}
+
+// Live returns the absolute path of the snapshot .latest of dir points to,
+// or an empty string if dir is not a poudriere package directory.
+func (pd *PoudriereDirs) Live(dir string) string {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+	if live, ok := pd.live[dir]; ok {
+		return live
+	}
+	live := ""
+	if info, err := os.Lstat(filepath.Join(dir, poudriereLatest)); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		if abs, err := realPath(filepath.Join(dir, poudriereLatest)); err == nil {
+			live = abs
+		}
+	}
+	pd.live[dir] = live
+	return live
+}
+
+// Skip reports whether the directory is a poudriere snapshot other than the live one.
+func (pd *PoudriereDirs) Skip(dir string) bool {
+	if !isSnapshotName(filepath.Base(dir)) {
+		return false
+	}
+	live := pd.Live(filepath.Dir(dir))
+	if live == "" {
+		return false
+	}
+	return !sameDir(dir, live)
+}
+
+// Dirs returns the poudriere package directories seen so far, sorted.
+func (pd *PoudriereDirs) Dirs() []string {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+	var dirs []string
+	for dir, live := range pd.live {
+		if live != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return ut.Arrange(dirs)
+}
+
+// snapshotTime returns the timestamp of a .real_<timestamp> snapshot name, or -1.
+func snapshotTime(name string) int64 {
+	if t, err := strconv.ParseInt(strings.TrimPrefix(name, poudriereSnapshot), 10, 64); err == nil {
+		return t
+	}
+	return -1
+}
+
+// errNoSnapshots - a poudriere package directory has nothing for -keep-snapshots to prune,
+// e.g. .latest points to a plain directory
+var errNoSnapshots = errors.New("no " + poudriereSnapshot + "* snapshots to prune")
+
+// staleSnapshots returns .real_* snapshots of a poudriere package directory exceeding keepCount,
+// oldest ones first to go; the live snapshot is always kept and counted.
+// Snapshots containing anything the live snapshot links to are kept as well.
+// It returns errNoSnapshots if the directory has no .real_* snapshots at all.
+func staleSnapshots(dir, live string, keepCount int) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []string
+	found := false
+	for _, entry := range entries {
+		if name := entry.Name(); entry.IsDir() && strings.HasPrefix(name, poudriereSnapshot) {
+			found = true
+			if path := filepath.Join(dir, name); !sameDir(path, live) {
+				snapshots = append(snapshots, path)
+			}
+		}
+	}
+	if !found {
+		return nil, errNoSnapshots
+	}
+
+	slices.SortFunc(snapshots, func(a, b string) int { // newest first
+		return cmp.Or(cmp.Compare(snapshotTime(filepath.Base(b)), snapshotTime(filepath.Base(a))), cmp.Compare(b, a))
+	})
+
+	if keepCount--; keepCount >= len(snapshots) {
+		return nil, nil
+	}
+	snapshots = snapshots[max(keepCount, 0):]
+
+	targets, err := linkTargets(live)
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []string
+	for _, snapshot := range snapshots {
+		abs, err := realPath(snapshot)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.ContainsFunc(targets, func(target string) bool {
+			return target == abs || strings.HasPrefix(target, abs+string(filepath.Separator))
+		}) {
+			stale = append(stale, snapshot)
+		}
+	}
+	return stale, nil
+}
+
+// inspectSnapshot returns the total size of the files of the snapshot and whether it contains a package
+// the protection protects, e.g. the only copy of a pinned version, which must not be pruned.
+func inspectSnapshot(snapshot string, protection Protection) (int64, bool, error) {
+	var size int64
+	protected := false
+	err := filepath.WalkDir(snapshot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		if name, ver := keyAndVersion(path); ver != nil && isPackageFile(d.Name()) && protection.Protects(name, *ver) {
+			protected = true
+		}
+		return nil
+	})
+	return size, protected, err
+}
+
+// realPath returns the absolute path with all symbolic links resolved.
+func realPath(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(resolved)
+}
+
+// sameDir reports whether the directory path resolves to the real path abs.
+func sameDir(path, abs string) bool {
+	p, err := realPath(path)
+	return err == nil && p == abs
+}
+
+// linkTargets returns absolute targets of all symbolic links found under dir.
+func linkTargets(dir string) ([]string, error) {
+	var targets []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(path), target)
+			}
+			if target, err = filepath.Abs(target); err != nil {
+				return err
+			}
+			targets = append(targets, target)
+		}
+		return nil
+	})
+	return targets, err
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// makePoudriereDir creates data/packages/<jail>-<tree>-like layout with the live snapshot .real_300.
+func makePoudriereDir(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "141amd64-default")
+	writeTestFiles(t, dir,
+		".real_100/All/pkg-1.0.pkg", ".real_100/meta.conf",
+		".real_200/All/pkg-1.1.pkg", ".real_200/meta.conf",
+		".real_300/All/pkg-1.2.pkg", ".real_300/meta.conf",
+		".building/All/pkg-1.3.pkg",
+	)
+	for name, target := range map[string]string{
+		".latest":   ".real_300",
+		"All":       ".latest/All",
+		"meta.conf": ".latest/meta.conf",
+	} {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestPoudriereDirsSkip(t *testing.T) {
+	dir := makePoudriereDir(t)
+	pd := NewPoudriereDirs()
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{".real_100", true},
+		{".real_200", true},
+		{".real_300", false},
+		{".building", true},
+		{"All", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pd.Skip(filepath.Join(dir, tt.name)); got != tt.want {
+				t.Errorf("Skip(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+
+	if got := pd.Dirs(); !reflect.DeepEqual(got, []string{dir}) {
+		t.Errorf("Dirs() = %v, want [%s]", got, dir)
+	}
+
+	plain := t.TempDir()
+	writeTestFiles(t, plain, ".real_1/All/pkg-1.0.pkg")
+	if pd.Skip(filepath.Join(plain, ".real_1")) {
+		t.Errorf("Skip() = true for a directory without .latest")
+	}
+}
+
+func TestStaleSnapshots(t *testing.T) {
+	dir := makePoudriereDir(t)
+	live := NewPoudriereDirs().Live(dir)
+	if filepath.Base(live) != ".real_300" {
+		t.Fatalf("Live() = %q", live)
+	}
+
+	tests := []struct {
+		keep int
+		want []string
+	}{
+		{1, []string{".real_200", ".real_100"}},
+		{2, []string{".real_100"}},
+		{3, nil},
+		{10, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.keep), func(t *testing.T) {
+			stale, err := staleSnapshots(dir, live, tt.keep)
+			if err != nil {
+				t.Fatalf("staleSnapshots() error = %v", err)
+			}
+			var got []string
+			for _, s := range stale {
+				got = append(got, filepath.Base(s))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("staleSnapshots(%d) = %v, want %v", tt.keep, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStaleSnapshotsNone(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, "All/pkg-1.0.pkg")
+	if err := os.Symlink("All", filepath.Join(dir, poudriereLatest)); err != nil {
+		t.Fatal(err)
+	}
+
+	if stale, err := staleSnapshots(dir, NewPoudriereDirs().Live(dir), 1); !errors.Is(err, errNoSnapshots) {
+		t.Errorf("staleSnapshots() = %v, %v; want errNoSnapshots", stale, err)
+	}
+}
+
+func TestStaleSnapshotsReferenced(t *testing.T) {
+	dir := makePoudriereDir(t)
+	if err := os.Symlink("../../.real_100/All/pkg-1.0.pkg", filepath.Join(dir, ".real_300", "All", "pkg-1.0.pkg")); err != nil {
+		t.Fatal(err)
+	}
+
+	stale, err := staleSnapshots(dir, NewPoudriereDirs().Live(dir), 1)
+	if err != nil {
+		t.Fatalf("staleSnapshots() error = %v", err)
+	}
+	if len(stale) != 1 || filepath.Base(stale[0]) != ".real_200" {
+		t.Errorf("staleSnapshots() = %v, want only .real_200", stale)
+	}
+}
+
+func TestInspectSnapshot(t *testing.T) {
+	dir := makePoudriereDir(t)
+	snapshot := filepath.Join(dir, ".real_100")
+
+	tests := []struct {
+		pin  string
+		want bool
+	}{
+		{"", false},
+		{"pkg-1.0", true},
+		{"pkg", true},
+		{"pkg-1.1", false},
+	}
+	for _, tt := range tests {
+		protection := Protection{}
+		if tt.pin != "" {
+			protection.Add(tt.pin)
+		}
+		size, protected, err := inspectSnapshot(snapshot, protection)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// writeTestFiles writes the names of the files as their contents
+		if protected != tt.want || size != int64(len(".real_100/All/pkg-1.0.pkg")+len(".real_100/meta.conf")) {
+			t.Errorf("inspectSnapshot(%q) = %d, %v; want %v", tt.pin, size, protected, tt.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const protectConfigName = appName + ".conf"
+
+// Protection - set of package names and "name-version" entries that must never be removed
+type Protection map[string]struct{}
+
+// Add adds entries separated by white space; anything after '#' is a comment.
+func (p Protection) Add(line string) {
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		line = line[:i]
+	}
+	for _, entry := range strings.Fields(line) {
+		p[entry] = struct{}{}
+	}
+}
+
+// Read adds entries from r, e.g. the output of "pkg query %n-%v".
+func (p Protection) Read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		p.Add(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// ReadFile adds entries from the file.
+func (p Protection) ReadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	// nolint:errcheck
+	defer f.Close()
+	return p.Read(f)
+}
+
+// ReadConfig adds entries from obsolete-packages.conf found in any of the configuration
+// directories and returns the paths of the files read.
+func (p Protection) ReadConfig(dirs []string) ([]string, error) {
+	var found []string
+	for _, dir := range dirs {
+		path := filepath.Join(dir, protectConfigName)
+		if err := p.ReadFile(path); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return found, err
+		}
+		found = append(found, path)
+	}
+	return found, nil
+}
+
+// Protects reports whether the package version is protected either by its name (pinned)
+// or by its "name-version".
+func (p Protection) Protects(name string, ver VersionType) bool {
+	if len(p) == 0 {
+		return false
+	}
+	if _, ok := p[name]; ok {
+		return true
+	}
+	if _, ok := p[name+"-"+ver.String()]; ok {
+		return true
+	}
+	base := filepath.Base(ver.Path)
+	_, ok := p[strings.TrimSuffix(base, filepath.Ext(base))]
+	return ok
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProtectionRead(t *testing.T) {
+	p := Protection{}
+	err := p.Read(strings.NewReader("bash-5.2.37\n# comment\n\npkg-1.21.3,1 curl-8.11.1_1 # trailing comment\n  zsh  \n"))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	want := Protection{"bash-5.2.37": {}, "pkg-1.21.3,1": {}, "curl-8.11.1_1": {}, "zsh": {}}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("Read() = %v, want %v", p, want)
+	}
+}
+
+func TestProtectionProtects(t *testing.T) {
+	p := Protection{}
+	p.Add("bash-5.2.37 pkg-1.21.3,1 curl-8.11.1_1 zsh py311-pip-24.0_0")
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/repo/All/bash-5.2.37.pkg", true},
+		{"/repo/All/bash-5.2.36.pkg", false},
+		{"/repo/All/pkg-1.21.3,1.pkg", true},
+		{"/repo/All/pkg-1.21.3.pkg", false},
+		{"/repo/All/curl-8.11.1_1.pkg", true},
+		{"/repo/All/curl-8.11.1.pkg", false},
+		{"/repo/All/zsh-5.8.pkg", true},
+		{"/repo/All/zsh-5.9_5.pkg", true},
+		{"/repo/All/py311-pip-24.0_0.pkg", true}, // matched by the file name
+		{"/repo/All/git-2.47.1.pkg", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			name, ver := keyAndVersion(tt.path)
+			if got := p.Protects(name, *ver); got != tt.want {
+				t.Errorf("Protects(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	if (Protection{}).Protects("bash", VersionType{}) {
+		t.Errorf("empty Protection protects")
+	}
+}
+
+func TestProtectionReadConfig(t *testing.T) {
+	dir1, dir2, dir3 := t.TempDir(), t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir1, protectConfigName), []byte("bash\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir3, protectConfigName), []byte("zsh-5.9\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := Protection{}
+	found, err := p.ReadConfig([]string{dir1, dir2, dir3})
+	if err != nil {
+		t.Fatalf("ReadConfig() error = %v", err)
+	}
+	if len(found) != 2 {
+		t.Errorf("ReadConfig() found %v", found)
+	}
+	if want := (Protection{"bash": {}, "zsh-5.9": {}}); !reflect.DeepEqual(p, want) {
+		t.Errorf("ReadConfig() = %v, want %v", p, want)
+	}
+}
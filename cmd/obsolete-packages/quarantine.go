@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	manifestName = "MANIFEST"
+	manifestSep  = "\t"
+	batchLayout  = "20060102T150405Z"
+	batchSuffix  = "."
+)
+
+// Quarantine moves files into a batch directory named after the current time,
+// preserving their layout relative to the repository root, and records every move
+// as "time original quarantined" in the MANIFEST file of the batch.
+type Quarantine struct {
+	batch    string
+	manifest *os.File
+	now      time.Time
+}
+
+// NewQuarantine creates a new batch directory within dir and its manifest.
+func NewQuarantine(dir string, now time.Time) (*Quarantine, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	now = now.UTC()
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	// another run within the same second gets the next numeric suffix, e.g. 20250707T120000Z.1
+	batch := filepath.Join(dir, now.Format(batchLayout))
+	for n := 1; ; n++ {
+		if err = os.Mkdir(batch, 0o755); !errors.Is(err, os.ErrExist) {
+			break
+		}
+		batch = filepath.Join(dir, now.Format(batchLayout)+batchSuffix+strconv.Itoa(n))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := os.OpenFile(filepath.Join(batch, manifestName), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Quarantine{batch: batch, manifest: manifest, now: now}, nil
+}
+
+// Move moves path found under the repository root into the batch directory.
+// The repository directory name itself is preserved, e.g. /usr/ports/packages/All/pkg-1.0.pkg
+// found under /usr/ports/packages/All goes to <batch>/All/pkg-1.0.pkg.
+// It returns the new location of the file.
+func (q *Quarantine) Move(root, path string) (string, error) {
+	src, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(filepath.Dir(root), src)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		rel = filepath.Base(src)
+	}
+
+	dst := filepath.Join(q.batch, rel)
+	if err = os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return "", err
+	}
+	if err = moveFile(src, dst); err != nil {
+		return "", err
+	}
+
+	_, err = fmt.Fprintln(q.manifest, q.now.Format(time.RFC3339)+manifestSep+src+manifestSep+dst)
+	return dst, err
+}
+
+// Close closes the manifest; an empty batch is removed.
+func (q *Quarantine) Close() error {
+	info, err := q.manifest.Stat()
+	if err = errors.Join(err, q.manifest.Close()); err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		return errors.Join(os.Remove(q.manifest.Name()), os.Remove(q.batch))
+	}
+	return nil
+}
+
+// moveFile renames src to dst, falling back to copying when they reside on different file systems.
+func moveFile(src, dst string) error {
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("%s: %w", dst, os.ErrExist)
+	}
+
+	err := os.Rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	if err = copyFile(src, dst); err != nil {
+		_ = os.Remove(dst)
+		return err
+	}
+	return os.Remove(src)
+}
+
+// copyFile copies a regular file preserving its permissions and modification time.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	// nolint:errcheck
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// manifestEntry - a single line of a quarantine manifest
+type manifestEntry struct {
+	Time                  time.Time
+	Original, Quarantined string
+}
+
+// readManifest parses a quarantine manifest.
+func readManifest(r io.Reader) ([]manifestEntry, error) {
+	var entries []manifestEntry
+	lineCount := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineCount++
+		fields := strings.Split(scanner.Text(), manifestSep)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: invalid number of fields: %d", lineCount, len(fields))
+		}
+		t, err := time.Parse(time.RFC3339, fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineCount, err)
+		}
+		entries = append(entries, manifestEntry{Time: t, Original: fields[1], Quarantined: fields[2]})
+	}
+	return entries, scanner.Err()
+}
+
+// restoreManifest moves files recorded in the manifest back to their original locations,
+// writing each restored path to w. Existing original files are never overwritten.
+// The manifest is removed once everything is restored, otherwise it is rewritten
+// with the entries that failed.
+func restoreManifest(manifestPath string, w io.Writer) (int, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return 0, err
+	}
+
+	entries, err := readManifest(bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", manifestPath, err)
+	}
+
+	var failed strings.Builder
+	var errs []error
+	count := 0
+	for _, e := range entries {
+		err = os.MkdirAll(filepath.Dir(e.Original), 0o755)
+		if err == nil {
+			err = moveFile(e.Quarantined, e.Original)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			failed.WriteString(e.Time.Format(time.RFC3339) + manifestSep + e.Original + manifestSep + e.Quarantined + "\n")
+			continue
+		}
+		count++
+		if _, err = fmt.Fprintln(w, e.Original); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if failed.Len() > 0 {
+		errs = append(errs, os.WriteFile(manifestPath, []byte(failed.String()), 0o644))
+	} else {
+		errs = append(errs, os.Remove(manifestPath))
+		removeEmptyDirs(filepath.Dir(manifestPath))
+	}
+
+	return count, errors.Join(errs...)
+}
+
+// purgeQuarantine removes batch directories of dir quarantined before olderThan
+// and returns their paths. Directories not looking like a batch are left untouched.
+func purgeQuarantine(dir string, olderThan time.Time) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var purged []string
+	var errs []error
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		name, _, _ := strings.Cut(entry.Name(), batchSuffix)
+		t, err := time.Parse(batchLayout, name)
+		if err != nil || !t.Before(olderThan) {
+			continue
+		}
+		batch := filepath.Join(dir, entry.Name())
+		if _, err = os.Stat(filepath.Join(batch, manifestName)); err != nil {
+			continue
+		}
+		if err = os.RemoveAll(batch); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		purged = append(purged, batch)
+	}
+	return purged, errors.Join(errs...)
+}
+
+// removeEmptyDirs removes dir and all its subdirectories that are left empty, deepest first.
+func removeEmptyDirs(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			removeEmptyDirs(filepath.Join(dir, entry.Name()))
+		}
+	}
+	_ = os.Remove(dir) // fails for a non-empty directory
+}
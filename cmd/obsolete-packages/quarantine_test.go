@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTestFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestQuarantineMoveAndRestore(t *testing.T) {
+	base := t.TempDir()
+	root, qdir := filepath.Join(base, "packages"), filepath.Join(base, "quarantine")
+	writeTestFiles(t, root, "All/pkg-1.0.pkg", "All/pkg-1.1.pkg", "Latest/pkg.pkg")
+
+	now := time.Date(2025, 7, 7, 12, 0, 0, 0, time.UTC)
+	q, err := NewQuarantine(qdir, now)
+	if err != nil {
+		t.Fatalf("NewQuarantine() error = %v", err)
+	}
+
+	src := filepath.Join(root, "All", "pkg-1.0.pkg")
+	dst, err := q.Move(root, src)
+	if err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	if err = q.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	batch := filepath.Join(qdir, "20250707T120000Z")
+	if want := filepath.Join(batch, "packages", "All", "pkg-1.0.pkg"); dst != want {
+		t.Errorf("Move() = %q, want %q", dst, want)
+	}
+	if _, err = os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("source still exists: %v", err)
+	}
+
+	manifestPath := filepath.Join(batch, manifestName)
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatalf("manifest: %v", err)
+	}
+	entries, err := readManifest(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("readManifest() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Original != src || entries[0].Quarantined != dst || !entries[0].Time.Equal(now) {
+		t.Errorf("readManifest() = %+v", entries)
+	}
+
+	var out bytes.Buffer
+	count, err := restoreManifest(manifestPath, &out)
+	if err != nil {
+		t.Fatalf("restoreManifest() error = %v", err)
+	}
+	if count != 1 || strings.TrimSpace(out.String()) != src {
+		t.Errorf("restoreManifest() = %d, %q", count, out.String())
+	}
+	if got, err := os.ReadFile(src); err != nil || string(got) != "All/pkg-1.0.pkg" {
+		t.Errorf("restored file = %q, %v", got, err)
+	}
+	if _, err = os.Stat(batch); !os.IsNotExist(err) {
+		t.Errorf("batch directory still exists: %v", err)
+	}
+}
+
+func TestQuarantineEmptyBatch(t *testing.T) {
+	qdir := t.TempDir()
+	q, err := NewQuarantine(qdir, time.Now())
+	if err != nil {
+		t.Fatalf("NewQuarantine() error = %v", err)
+	}
+	if err = q.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if entries, _ := os.ReadDir(qdir); len(entries) != 0 {
+		t.Errorf("empty batch left behind: %v", entries)
+	}
+}
+
+func TestQuarantineSameSecond(t *testing.T) {
+	qdir := t.TempDir()
+	now := time.Date(2025, 7, 7, 12, 0, 0, 0, time.UTC)
+
+	var batches []string
+	for range 3 {
+		q, err := NewQuarantine(qdir, now)
+		if err != nil {
+			t.Fatalf("NewQuarantine() error = %v", err)
+		}
+		batches = append(batches, filepath.Base(q.batch))
+	}
+	if want := []string{"20250707T120000Z", "20250707T120000Z.1", "20250707T120000Z.2"}; strings.Join(batches, " ") != strings.Join(want, " ") {
+		t.Errorf("batches = %v, want %v", batches, want)
+	}
+}
+
+func TestRestoreManifestKeepsExisting(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "All")
+	writeTestFiles(t, root, "pkg-1.0.pkg")
+
+	q, err := NewQuarantine(filepath.Join(base, "q"), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(root, "pkg-1.0.pkg")
+	if _, err = q.Move(root, src); err != nil {
+		t.Fatal(err)
+	}
+	_ = q.Close()
+
+	writeTestFiles(t, root, "pkg-1.0.pkg") // the package reappeared meanwhile
+
+	manifestPath := filepath.Join(q.batch, manifestName)
+	count, err := restoreManifest(manifestPath, &bytes.Buffer{})
+	if err == nil || count != 0 {
+		t.Fatalf("restoreManifest() = %d, %v; want an error", count, err)
+	}
+	if data, err := os.ReadFile(manifestPath); err != nil || !strings.Contains(string(data), src) {
+		t.Errorf("manifest must keep failed entries: %q, %v", data, err)
+	}
+}
+
+func TestPurgeQuarantine(t *testing.T) {
+	qdir := t.TempDir()
+	writeTestFiles(t, qdir,
+		"20250101T000000Z/"+manifestName, "20250101T000000Z/All/pkg-1.0.pkg",
+		"20250101T000000Z.1/"+manifestName, "20250101T000000Z.1/All/pkg-1.0.pkg",
+		"20250701T000000Z/"+manifestName, "20250701T000000Z/All/pkg-1.1.pkg",
+		"20240101T000000Z/All/foreign.pkg", // no manifest
+		"keep-me/"+manifestName,
+	)
+
+	purged, err := purgeQuarantine(qdir, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("purgeQuarantine() error = %v", err)
+	}
+	if len(purged) != 2 || filepath.Base(purged[0]) != "20250101T000000Z" || filepath.Base(purged[1]) != "20250101T000000Z.1" {
+		t.Errorf("purgeQuarantine() = %v", purged)
+	}
+
+	for _, name := range []string{"20250701T000000Z", "20240101T000000Z", "keep-me"} {
+		if _, err = os.Stat(filepath.Join(qdir, name)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
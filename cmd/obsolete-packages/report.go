@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats of -format
+const (
+	formatJSON  = "json"
+	formatCSV   = "csv"
+	formatTable = "table"
+)
+
+var reportFormats = []string{formatJSON, formatCSV, formatTable}
+
+// FileReport - a package file and whether it has been removed (deleted or moved)
+type FileReport struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	Size    int64  `json:"size"`
+	Origin  string `json:"origin,omitempty"`
+	Removed bool   `json:"removed,omitempty"`
+}
+
+// GroupReport - kept and obsolete versions of a package
+type GroupReport struct {
+	Key      string       `json:"key"`
+	Name     string       `json:"name"`
+	Kept     []FileReport `json:"kept"`
+	Obsolete []FileReport `json:"obsolete"`
+}
+
+// Sizes returns the total size of obsolete files and of those actually removed.
+func (g *GroupReport) Sizes() (int64, int64) {
+	var reclaimable, reclaimed int64
+	for _, f := range g.Obsolete {
+		reclaimable += f.Size
+		if f.Removed {
+			reclaimed += f.Size
+		}
+	}
+	return reclaimable, reclaimed
+}
+
+// Summary - totals over all groups
+type Summary struct {
+	Packages    int   `json:"packages"`
+	Files       int   `json:"files"`
+	Reclaimable int64 `json:"reclaimable"`
+	Reclaimed   int64 `json:"reclaimed"`
+}
+
+// summarize sums up obsolete files of all groups.
+func summarize(groups []GroupReport) Summary {
+	var s Summary
+	for i := range groups {
+		reclaimable, reclaimed := groups[i].Sizes()
+		s.Packages++
+		s.Files += len(groups[i].Obsolete)
+		s.Reclaimable += reclaimable
+		s.Reclaimed += reclaimed
+	}
+	return s
+}
+
+// String -
+func (s Summary) String() string {
+	return fmt.Sprintf("%d obsolete file(s) of %d package(s), %s reclaimable, %s reclaimed",
+		s.Files, s.Packages, humanSize(s.Reclaimable), humanSize(s.Reclaimed))
+}
+
+// humanSize formats a number of bytes using binary units, e.g. 1536 → "1.5 KiB".
+func humanSize(n int64) string {
+	const units = "KMGTPE"
+	if n < 1024 {
+		return strconv.FormatInt(n, 10) + " B"
+	}
+	f, i := float64(n)/1024, 0
+	for ; f >= 1024 && i < len(units)-1; i++ {
+		f /= 1024
+	}
+	return strconv.FormatFloat(f, 'f', 1, 64) + " " + units[i:i+1] + "iB"
+}
+
+// versionsOf joins versions of the files, e.g. "1.2, 1.1".
+func versionsOf(files []FileReport) string {
+	versions := make([]string, len(files))
+	for i := range files {
+		versions[i] = files[i].Version
+	}
+	return strings.Join(versions, ", ")
+}
+
+// writeReport writes the groups in one of reportFormats.
+func writeReport(w io.Writer, format string, groups []GroupReport) error {
+	switch format {
+	case formatJSON:
+		if groups == nil {
+			groups = []GroupReport{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Packages []GroupReport `json:"packages"`
+			Summary  Summary       `json:"summary"`
+		}{groups, summarize(groups)})
+
+	case formatCSV:
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"key", "name", "status", "version", "size", "path"})
+		for _, g := range groups {
+			for _, f := range g.Kept {
+				_ = cw.Write([]string{g.Key, g.Name, "kept", f.Version, strconv.FormatInt(f.Size, 10), f.Path})
+			}
+			for _, f := range g.Obsolete {
+				status := "obsolete"
+				if f.Removed {
+					status = "removed"
+				}
+				_ = cw.Write([]string{g.Key, g.Name, status, f.Version, strconv.FormatInt(f.Size, 10), f.Path})
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case formatTable:
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "KEY\tKEPT\tOBSOLETE\tFILES\tRECLAIMABLE\tRECLAIMED")
+		for i := range groups {
+			reclaimable, reclaimed := groups[i].Sizes()
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\n", groups[i].Key, versionsOf(groups[i].Kept), versionsOf(groups[i].Obsolete),
+				len(groups[i].Obsolete), humanSize(reclaimable), humanSize(reclaimed))
+		}
+		s := summarize(groups)
+		_, _ = fmt.Fprintf(tw, "TOTAL\t\t\t%d\t%s\t%s\n", s.Files, humanSize(s.Reclaimable), humanSize(s.Reclaimed))
+		return tw.Flush()
+	}
+	return fmt.Errorf("unknown format: %q", format)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testReports() []GroupReport {
+	return []GroupReport{
+		{
+			Key: "bash", Name: "bash",
+			Kept:     []FileReport{{Path: "All/bash-5.2.37.pkg", Version: "5.2.37", Size: 2048}},
+			Obsolete: []FileReport{{Path: "All/bash-5.2.26.pkg", Version: "5.2.26", Size: 1024, Removed: true}, {Path: "All/bash-5.1.pkg", Version: "5.1", Size: 512}},
+		},
+		{
+			Key: "pkg", Name: "pkg",
+			Kept:     []FileReport{{Path: "All/pkg-1.21.3.pkg", Version: "1.21.3", Size: 4096}},
+			Obsolete: []FileReport{{Path: "All/pkg-1.20.9.pkg", Version: "1.20.9", Size: 3 << 20}},
+		},
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	got := summarize(testReports())
+	want := Summary{Packages: 2, Files: 3, Reclaimable: 1024 + 512 + 3<<20, Reclaimed: 1024}
+	if got != want {
+		t.Errorf("summarize() = %+v, want %+v", got, want)
+	}
+	if s := got.String(); s != "3 obsolete file(s) of 2 package(s), 3.0 MiB reclaimable, 1.0 KiB reclaimed" {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestHumanSize(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 << 30, "5.0 GiB"},
+		{1 << 62, "4.0 EiB"},
+	}
+	for _, tt := range tests {
+		if got := humanSize(tt.n); got != tt.want {
+			t.Errorf("humanSize(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := writeReport(&buf, formatJSON, testReports()); err != nil {
+			t.Fatal(err)
+		}
+		var got struct {
+			Packages []GroupReport `json:"packages"`
+			Summary  Summary       `json:"summary"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Packages) != 2 || got.Packages[0].Obsolete[0] != testReports()[0].Obsolete[0] || got.Summary.Files != 3 {
+			t.Errorf("unexpected JSON report:\n%s", buf.String())
+		}
+	})
+
+	t.Run("json empty", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := writeReport(&buf, formatJSON, nil); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), `"packages": []`) {
+			t.Errorf("unexpected JSON report:\n%s", buf.String())
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := writeReport(&buf, formatCSV, testReports()); err != nil {
+			t.Fatal(err)
+		}
+		want := "key,name,status,version,size,path\n" +
+			"bash,bash,kept,5.2.37,2048,All/bash-5.2.37.pkg\n" +
+			"bash,bash,removed,5.2.26,1024,All/bash-5.2.26.pkg\n" +
+			"bash,bash,obsolete,5.1,512,All/bash-5.1.pkg\n" +
+			"pkg,pkg,kept,1.21.3,4096,All/pkg-1.21.3.pkg\n" +
+			"pkg,pkg,obsolete,1.20.9,3145728,All/pkg-1.20.9.pkg\n"
+		if buf.String() != want {
+			t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+		}
+	})
+
+	t.Run("table", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := writeReport(&buf, formatTable, testReports()); err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 4 || !strings.HasPrefix(lines[1], "bash ") || !strings.Contains(lines[1], "5.2.26, 5.1") ||
+			!strings.HasPrefix(lines[3], "TOTAL") || !strings.Contains(lines[3], "3.0 MiB") {
+			t.Errorf("unexpected table:\n%s", buf.String())
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		if err := writeReport(&bytes.Buffer{}, "xml", nil); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
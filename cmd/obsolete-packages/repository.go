@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// repositoryMarkers - files found in the root of a pkg(8) repository
+var repositoryMarkers = []string{"meta.conf", "meta", "packagesite.pkg", "packagesite.txz", "packagesite.yaml"}
+
+// isABI reports whether a path component looks like a pkg(8) ABI, e.g. "FreeBSD:14:amd64".
+func isABI(name string) bool {
+	parts := strings.Split(name, ":")
+	if len(parts) < 3 || parts[0] == "" || parts[1] == "" {
+		return false
+	}
+	ascii := func(s string, class func(rune) bool) bool {
+		for _, r := range s {
+			if r > unicode.MaxASCII || !class(r) {
+				return false
+			}
+		}
+		return true
+	}
+	return ascii(parts[0], unicode.IsLetter) && ascii(parts[1], unicode.IsDigit) && parts[2] != ""
+}
+
+// abiRoot returns the part of the directory path up to its deepest ABI component, or an empty string.
+// Example: abiRoot("/pkg/FreeBSD:14:amd64/latest/All") → "/pkg/FreeBSD:14:amd64"
+func abiRoot(dir string) string {
+	for d := dir; ; {
+		if isABI(filepath.Base(d)) {
+			return d
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return ""
+		}
+		d = parent
+	}
+}
+
+// hasRepositoryMarker reports whether the directory contains any of repositoryMarkers.
+func hasRepositoryMarker(dir string) bool {
+	for _, name := range repositoryMarkers {
+		if _, err := os.Lstat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// RepositoryFinder determines repository roots of directories, caching the results; it is safe for concurrent use.
+// Every directory is looked at once, concurrent lookups of other directories do not wait for it.
+type RepositoryFinder struct {
+	mu      sync.Mutex
+	markers map[[2]string]*markedDir // {directory, top} -> the nearest directory with a repository marker
+}
+
+// markedDir - the nearest directory with a repository marker, or "", determined once
+type markedDir struct {
+	once sync.Once
+	root string
+}
+
+// NewRepositoryFinder -
+func NewRepositoryFinder() *RepositoryFinder {
+	return &RepositoryFinder{markers: map[[2]string]*markedDir{}}
+}
+
+// Root returns the repository root of dir, which is located under top:
+// the nearest directory (dir itself or its parent up to top) containing repository metadata,
+// otherwise the deepest ABI path component, otherwise top.
+func (rf *RepositoryFinder) Root(dir, top string) string {
+	if root := rf.marked(dir, top); root != "" {
+		return root
+	}
+	if root := abiRoot(dir); root != "" {
+		return root
+	}
+	return top
+}
+
+func (rf *RepositoryFinder) marked(dir, top string) string {
+	rf.mu.Lock()
+	m, ok := rf.markers[[2]string{dir, top}]
+	if !ok {
+		m = &markedDir{}
+		rf.markers[[2]string{dir, top}] = m
+	}
+	rf.mu.Unlock()
+
+	m.once.Do(func() {
+		switch parent := filepath.Dir(dir); {
+		case hasRepositoryMarker(dir):
+			m.root = dir
+		case dir != top && parent != dir:
+			m.root = rf.marked(parent, top)
+		}
+	})
+	return m.root
+}
+
+// groupKey returns the key packages are grouped by: the package name itself
+// for global grouping, otherwise the name within the repository root, e.g. "/pkg/FreeBSD:14:amd64/bash".
+func groupKey(repo, name string) string {
+	if repo == "" {
+		return name
+	}
+	return filepath.Join(repo, name)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIsABI(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"FreeBSD:14:amd64", true},
+		{"FreeBSD:13:aarch64", true},
+		{"freebsd:15:x86:64", true},
+		{"FreeBSD:14:", false},
+		{"FreeBSD::amd64", false},
+		{":14:amd64", false},
+		{"FreeBSD:x:amd64", false},
+		{"FreeBSD14:amd64", false},
+		{"All", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isABI(tt.name); got != tt.want {
+				t.Errorf("isABI(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAbiRoot(t *testing.T) {
+	tests := []struct {
+		dir, want string
+	}{
+		{"/pkg/FreeBSD:14:amd64/latest/All", "/pkg/FreeBSD:14:amd64"},
+		{"/pkg/FreeBSD:14:amd64", "/pkg/FreeBSD:14:amd64"},
+		{"/mirror/FreeBSD:13:amd64/FreeBSD:14:amd64/All", "/mirror/FreeBSD:13:amd64/FreeBSD:14:amd64"},
+		{"/usr/ports/packages/All", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			if got := abiRoot(tt.dir); got != tt.want {
+				t.Errorf("abiRoot(%q) = %q, want %q", tt.dir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepositoryFinderRoot(t *testing.T) {
+	base := t.TempDir()
+	writeTestFiles(t, base,
+		"jail1/meta.conf", "jail1/All/pkg-1.0.pkg",
+		"jail2/packagesite.pkg", "jail2/All/pkg-1.1.pkg",
+		"FreeBSD:14:amd64/latest/All/pkg-1.2.pkg",
+		"plain/All/pkg-1.3.pkg",
+	)
+
+	tests := []struct {
+		dir, top, want string
+	}{
+		{"jail1/All", "", "jail1"},
+		{"jail1", "", "jail1"},
+		{"jail2/All", "", "jail2"},
+		{"FreeBSD:14:amd64/latest/All", "", "FreeBSD:14:amd64"},
+		{"plain/All", "", ""},
+		{"plain/All", "plain/All", "plain/All"},
+		{"jail1/All", "jail1/All", "jail1/All"}, // metadata above top is not looked for
+	}
+
+	rf := NewRepositoryFinder()
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			top := base
+			if tt.top != "" {
+				top = filepath.Join(base, tt.top)
+			}
+			want := base
+			if tt.want != "" {
+				want = filepath.Join(base, tt.want)
+			}
+			if got := rf.Root(filepath.Join(base, tt.dir), top); got != want {
+				t.Errorf("Root(%q, %q) = %q, want %q", tt.dir, tt.top, got, want)
+			}
+		})
+	}
+}
+
+func TestGroupKey(t *testing.T) {
+	if got := groupKey("", "bash"); got != "bash" {
+		t.Errorf("groupKey() = %q, want %q", got, "bash")
+	}
+	if got, want := groupKey("/pkg/FreeBSD:14:amd64", "bash"), filepath.Join("/pkg/FreeBSD:14:amd64", "bash"); got != want {
+		t.Errorf("groupKey() = %q, want %q", got, want)
+	}
+}
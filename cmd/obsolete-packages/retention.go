@@ -0,0 +1,16 @@
+package main
+
+import "time"
+
+// obsoleteVersions returns the versions to be removed from a slice sorted by compareVersionDesc:
+// the first keepCount versions are always kept, as well as any version modified after keepAfter
+// (a zero keepAfter disables the age check) and any version protected reports true for (if not nil).
+func obsoleteVersions(sorted []VersionType, keepCount int, keepAfter time.Time, protected func(VersionType) bool) []VersionType {
+	var result []VersionType
+	for i := keepCount; i < len(sorted); i++ {
+		if (keepAfter.IsZero() || !sorted[i].ModTime.After(keepAfter)) && (protected == nil || !protected(sorted[i])) {
+			result = append(result, sorted[i])
+		}
+	}
+	return result
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestObsoleteVersions(t *testing.T) {
+	now := time.Now()
+	sorted := []VersionType{
+		{Path: "pkg-1.4.pkg", ModTime: now.AddDate(0, 0, -1)},
+		{Path: "pkg-1.3.pkg", ModTime: now.AddDate(0, 0, -3)},
+		{Path: "pkg-1.2.pkg", ModTime: now.AddDate(0, 0, -10)},
+		{Path: "pkg-1.1.pkg", ModTime: now.AddDate(0, 0, -2)}, // rebuilt recently
+		{Path: "pkg-1.0.pkg", ModTime: now.AddDate(0, 0, -30)},
+	}
+
+	tests := []struct {
+		name      string
+		keepCount int
+		keepAfter time.Time
+		want      []string
+	}{
+		{"keep newest", 1, time.Time{}, []string{"pkg-1.3.pkg", "pkg-1.2.pkg", "pkg-1.1.pkg", "pkg-1.0.pkg"}},
+		{"keep three", 3, time.Time{}, []string{"pkg-1.1.pkg", "pkg-1.0.pkg"}},
+		{"keep all", 5, time.Time{}, nil},
+		{"keep more than exists", 10, time.Time{}, nil},
+		{"keep five days", 1, now.AddDate(0, 0, -5), []string{"pkg-1.2.pkg", "pkg-1.0.pkg"}},
+		{"keep two and five days", 2, now.AddDate(0, 0, -5), []string{"pkg-1.2.pkg", "pkg-1.0.pkg"}},
+		{"keep three and a day", 3, now.AddDate(0, 0, -1), []string{"pkg-1.1.pkg", "pkg-1.0.pkg"}},
+		{"keep a year", 1, now.AddDate(-1, 0, 0), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, ver := range obsoleteVersions(sorted, tt.keepCount, tt.keepAfter, nil) {
+				got = append(got, ver.Path)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("obsoleteVersions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObsoleteVersionsProtected(t *testing.T) {
+	sorted := []VersionType{{Path: "pkg-1.2.pkg"}, {Path: "pkg-1.1.pkg"}, {Path: "pkg-1.0.pkg"}}
+	protected := func(ver VersionType) bool { return ver.Path == "pkg-1.1.pkg" }
+
+	var got []string
+	for _, ver := range obsoleteVersions(sorted, 1, time.Time{}, protected) {
+		got = append(got, ver.Path)
+	}
+	if want := []string{"pkg-1.0.pkg"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("obsoleteVersions() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// errQuit - the operator has quit the interactive review
+var errQuit = errors.New("review quit")
+
+const reviewPrompt = "[a]ccept, [s]kip, flip [1-%d ...], [q]uit? "
+
+// reviewVersions shows the versions of a package sorted by compareVersionDesc with the proposed decisions
+// and lets the operator accept them, skip the package (nothing is removed), or flip individual versions
+// between keep and delete. Versions the protected predicate, if any, reports are shown as pinned
+// and cannot be flipped. It returns the versions to be removed, or errQuit if the operator quits
+// or the input ends.
+func reviewVersions(r *bufio.Reader, w io.Writer, key string, versions, obsolete []VersionType, protected func(VersionType) bool) ([]VersionType, error) {
+	remove, pinned := make([]bool, len(versions)), make([]bool, len(versions))
+	for i := range versions {
+		pinned[i] = protected != nil && protected(versions[i])
+		remove[i] = !pinned[i] && versionsContain(obsolete, versions[i].Path)
+	}
+
+	for {
+		_, _ = fmt.Fprintf(w, "\n%s\n", key)
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		for i, ver := range versions {
+			decision := "keep"
+			if pinned[i] {
+				decision = "pinned"
+			} else if remove[i] {
+				decision = "delete"
+			}
+			_, _ = fmt.Fprintf(tw, "  %d\t%s\t%s\t%s\t%s\t%s\n", i+1, decision, ver.String(), humanSize(ver.Size),
+				ver.ModTime.Format("2006-01-02 15:04"), ver.Path)
+		}
+		_ = tw.Flush()
+		_, _ = fmt.Fprintf(w, reviewPrompt, len(versions))
+
+		line, err := r.ReadString('\n')
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			if line == "" {
+				_, _ = fmt.Fprintln(w)
+				return nil, errQuit
+			}
+		}
+
+		switch answer := strings.ToLower(strings.TrimSpace(line)); answer {
+		case "", "a", "accept":
+			var result []VersionType
+			for i := range versions {
+				if remove[i] {
+					result = append(result, versions[i])
+				}
+			}
+			return result, nil
+		case "s", "skip":
+			return nil, nil
+		case "q", "quit":
+			return nil, errQuit
+		default:
+			for _, field := range strings.Fields(answer) {
+				n, err := strconv.Atoi(field)
+				switch {
+				case err != nil || n < 1 || n > len(versions):
+					_, _ = fmt.Fprintf(w, "Invalid answer: %s\n", field)
+				case pinned[n-1]:
+					_, _ = fmt.Fprintf(w, "Pinned version is kept: %s\n", versions[n-1].Path)
+				default:
+					remove[n-1] = !remove[n-1]
+				}
+			}
+		}
+	}
+}
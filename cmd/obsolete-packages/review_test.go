@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReviewVersions(t *testing.T) {
+	versions := []VersionType{}
+	for _, path := range []string{"bash-5.2.37.pkg", "bash-5.2.26.pkg", "bash-5.1.pkg"} {
+		_, ver := keyAndVersion(path)
+		ver.Size = 1536
+		versions = append(versions, *ver)
+	}
+	proposed := versions[1:]
+
+	tests := []struct {
+		name, input string
+		want        []string
+		wantErr     error
+	}{
+		{"accept", "a\n", []string{"bash-5.2.26.pkg", "bash-5.1.pkg"}, nil},
+		{"accept by default", "\n", []string{"bash-5.2.26.pkg", "bash-5.1.pkg"}, nil},
+		{"skip", "s\n", nil, nil},
+		{"flip", "2\na\n", []string{"bash-5.1.pkg"}, nil},
+		{"flip several", "1 2 3\nA\n", []string{"bash-5.2.37.pkg"}, nil},
+		{"flip twice", "3\n3\naccept\n", []string{"bash-5.2.26.pkg", "bash-5.1.pkg"}, nil},
+		{"invalid answers", "0 4 x\na", []string{"bash-5.2.26.pkg", "bash-5.1.pkg"}, nil},
+		{"quit", "q\n", nil, errQuit},
+		{"end of input", "2\n", nil, errQuit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			result, err := reviewVersions(bufio.NewReader(strings.NewReader(tt.input)), &out, "bash", versions, proposed, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("reviewVersions() error = %v, want %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, ver := range result {
+				got = append(got, ver.Path)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("reviewVersions() = %q, want %q", got, tt.want)
+			}
+			if !strings.Contains(out.String(), "delete  5.2.26  1.5 KiB") || !strings.Contains(out.String(), "flip [1-3 ...]") {
+				t.Errorf("unexpected output:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestReviewVersions_Pinned(t *testing.T) {
+	versions := []VersionType{}
+	for _, path := range []string{"bash-5.2.37.pkg", "bash-5.2.26.pkg", "bash-5.1.pkg"} {
+		_, ver := keyAndVersion(path)
+		versions = append(versions, *ver)
+	}
+	protected := func(ver VersionType) bool { return ver.Path == "bash-5.1.pkg" }
+
+	var out bytes.Buffer
+	input := "3 2\na\n"
+	result, err := reviewVersions(bufio.NewReader(strings.NewReader(input)), &out, "bash", versions, versions[1:], protected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 0 {
+		t.Errorf("reviewVersions() = %v, want nothing removed", result)
+	}
+	if !strings.Contains(out.String(), "pinned  5.1") || !strings.Contains(out.String(), "Pinned version is kept: bash-5.1.pkg") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
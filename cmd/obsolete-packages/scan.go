@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Scanner walks package directories concurrently and groups package files by key.
+// Directories are read and package files are stat'ed by at most Jobs goroutines at a time.
+type Scanner struct {
+	Jobs          int
+	Filter        *fileFilter
+	Finder        *RepositoryFinder // nil groups packages by name across all repositories
+	Poudriere     *PoudriereDirs
+	Catalogues    *Catalogues // nil parses package names and versions from file names only
+	QuarantineDir string      // absolute path of the directory not to be scanned, or ""
+	Log           io.Writer   // verbose messages, or nil
+
+	sem   chan struct{}
+	wg    sync.WaitGroup
+	mu    sync.Mutex
+	data  map[string]*[]VersionType
+	links []PackageLink
+}
+
+// Scan walks the directories and returns package versions grouped by key, each group sorted by path,
+// and links to package files sorted by path.
+func (s *Scanner) Scan(roots []string) (map[string]*[]VersionType, []PackageLink) {
+	s.sem, s.data, s.links = make(chan struct{}, max(s.Jobs, 1)), map[string]*[]VersionType{}, nil
+
+	for _, root := range roots {
+		root = filepath.Clean(root)
+		if info, err := os.Lstat(root); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			if resolved, err := filepath.EvalSymlinks(root); err == nil { // e.g. poudriere's .latest or All
+				root = resolved
+			}
+		}
+		info, err := os.Lstat(root)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ERROR:", err)
+			continue
+		}
+		if info.IsDir() {
+			s.wg.Add(1)
+			go s.walk(root, root)
+		} else {
+			s.file(root, root, fs.FileInfoToDirEntry(info))
+		}
+	}
+	s.wg.Wait()
+
+	for _, versions := range s.data {
+		slices.SortFunc(*versions, func(a, b VersionType) int { return strings.Compare(a.Path, b.Path) })
+	}
+	slices.SortFunc(s.links, func(a, b PackageLink) int { return strings.Compare(a.Path, b.Path) })
+	return s.data, s.links
+}
+
+func (s *Scanner) logf(format string, a ...any) {
+	if s.Log != nil {
+		_, _ = fmt.Fprintf(s.Log, format, a...)
+	}
+}
+
+// walk reads the directory, handles its files and starts walking its subdirectories.
+func (s *Scanner) walk(dir, root string) {
+	defer s.wg.Done()
+	s.sem <- struct{}{}
+	defer func() { <-s.sem }()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR:", err)
+		return
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() {
+			if s.QuarantineDir != "" {
+				if abs, err := filepath.Abs(path); err == nil && abs == s.QuarantineDir {
+					continue
+				}
+			}
+			if s.Poudriere != nil && s.Poudriere.Skip(path) {
+				s.logf("Skipped snapshot: %s\n", path)
+				continue
+			}
+			s.wg.Add(1)
+			go s.walk(path, root)
+			continue
+		}
+		s.file(path, root, entry)
+	}
+}
+
+// file handles a single directory entry which is not a directory.
+func (s *Scanner) file(path, root string, entry fs.DirEntry) {
+	if entry.Type()&fs.ModeSymlink != 0 {
+		if !isPackageFile(path) {
+			return
+		}
+		link, err := readPackageLink(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			return
+		}
+		s.mu.Lock()
+		s.links = append(s.links, link)
+		s.mu.Unlock()
+		return
+	}
+
+	if !entry.Type().IsRegular() {
+		return
+	}
+	if s.Filter != nil && !s.Filter.Accept(path) {
+		s.logf("Skipped: %s\n", path)
+		return
+	}
+
+	k, ver := "", (*VersionType)(nil)
+	if s.Catalogues != nil {
+		k, ver = s.Catalogues.Lookup(path, root)
+	}
+	if k == "" {
+		k, ver = keyAndVersion(path)
+	}
+	if k == "" {
+		s.logf("Empty key: %s\n", path)
+		return
+	}
+
+	info, err := entry.Info()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		return
+	}
+	ver.ModTime, ver.Size, ver.Root = info.ModTime(), info.Size(), root
+	if s.Finder != nil {
+		k = groupKey(s.Finder.Root(filepath.Dir(ver.Path), root), k)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if versions, ok := s.data[k]; ok {
+		if !versionsContain(*versions, ver.Path) {
+			*versions = append(*versions, *ver)
+		}
+	} else {
+		s.data[k] = &[]VersionType{*ver}
+	}
+}
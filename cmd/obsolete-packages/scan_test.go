@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "packages")
+	writeTestFiles(t, root,
+		"FreeBSD:14:amd64/meta.conf",
+		"FreeBSD:14:amd64/All/bash-5.2.37.pkg",
+		"FreeBSD:14:amd64/All/bash-5.2.26.pkg",
+		"FreeBSD:14:amd64/All/pkg-1.21.3.pkg",
+		"FreeBSD:14:amd64/packagesite.pkg",
+		"FreeBSD:14:amd64/All/README.txt",
+		"FreeBSD:15:amd64/All/bash-5.2.37.pkg",
+		"quarantine/20250707T120000Z/All/bash-5.1.pkg",
+	)
+	if err := os.MkdirAll(filepath.Join(root, "FreeBSD:14:amd64", "Latest"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../All/pkg-1.21.3.pkg", filepath.Join(root, "FreeBSD:14:amd64", "Latest", "pkg.pkg")); err != nil {
+		t.Fatal(err)
+	}
+
+	scan := func(jobs int) (map[string]*[]VersionType, []PackageLink) {
+		s := &Scanner{Jobs: jobs, Filter: &fileFilter{}, Finder: NewRepositoryFinder(), Poudriere: NewPoudriereDirs(),
+			QuarantineDir: filepath.Join(root, "quarantine")}
+		return s.Scan([]string{root})
+	}
+
+	data, links := scan(1)
+	paths := map[string][]string{}
+	for k, versions := range data {
+		for _, ver := range *versions {
+			rel, _ := filepath.Rel(root, ver.Path)
+			paths[k] = append(paths[k], rel)
+			if ver.Size != int64(len(rel)) || ver.Root != root || ver.ModTime.IsZero() {
+				t.Errorf("unexpected file info of %s: %+v", rel, ver)
+			}
+		}
+	}
+	want := map[string][]string{
+		filepath.Join(root, "FreeBSD:14:amd64", "bash"): {"FreeBSD:14:amd64/All/bash-5.2.26.pkg", "FreeBSD:14:amd64/All/bash-5.2.37.pkg"},
+		filepath.Join(root, "FreeBSD:14:amd64", "pkg"):  {"FreeBSD:14:amd64/All/pkg-1.21.3.pkg"},
+		filepath.Join(root, "FreeBSD:15:amd64", "bash"): {"FreeBSD:15:amd64/All/bash-5.2.37.pkg"},
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("Scan() = %v, want %v", paths, want)
+	}
+	if len(links) != 1 || links[0].Target != filepath.Join(root, "FreeBSD:14:amd64", "All", "pkg-1.21.3.pkg") {
+		t.Errorf("Scan() links = %+v", links)
+	}
+
+	for _, jobs := range []int{2, 8} {
+		if got, gotLinks := scan(jobs); !reflect.DeepEqual(got, data) || !reflect.DeepEqual(gotLinks, links) {
+			t.Errorf("Scan() with %d jobs differs from a single job", jobs)
+		}
+	}
+}
+
+func TestScanFile(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, "bash-5.2.37.pkg")
+	data, _ := (&Scanner{Jobs: 1}).Scan([]string{filepath.Join(dir, "bash-5.2.37.pkg"), filepath.Join(dir, "missing")})
+	if versions, ok := data["bash"]; !ok || len(*versions) != 1 {
+		t.Errorf("Scan() = %v", data)
+	}
+}
+
+// makeSyntheticTree creates dirs directories with files package files each.
+func makeSyntheticTree(b *testing.B, dirs, files int) string {
+	b.Helper()
+	root := b.TempDir()
+	for d := 0; d < dirs; d++ {
+		dir := filepath.Join(root, fmt.Sprintf("FreeBSD:%d:amd64", d), "All")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			b.Fatal(err)
+		}
+		for f := 0; f < files; f++ {
+			name := fmt.Sprintf("port%d-1.%d_%d.pkg", f/4, f%4, d%3)
+			if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	return root
+}
+
+func BenchmarkScan(b *testing.B) {
+	root := makeSyntheticTree(b, 64, 256)
+	for _, jobs := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("jobs=%d", jobs), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				s := &Scanner{Jobs: jobs, Filter: &fileFilter{}, Finder: NewRepositoryFinder(), Poudriere: NewPoudriereDirs()}
+				if data, _ := s.Scan([]string{root}); len(data) == 0 {
+					b.Fatal("nothing found")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const auditLayout = time.RFC3339
+
+// shellQuote quotes a string for a POSIX shell: it is enclosed in single quotes,
+// and every single quote inside ends the quoted part, is escaped with a backslash and starts a new one.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellComment makes a string safe to be put into a shell comment: control characters,
+// including newlines which would end the comment, are replaced with "?".
+func shellComment(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return '?'
+		}
+		return r
+	}, s)
+}
+
+// Script writes a POSIX shell script removing obsolete packages instead of removing them.
+type Script struct {
+	w     io.Writer
+	count int
+}
+
+// NewScript writes the script header.
+func NewScript(w io.Writer, now time.Time) (*Script, error) {
+	_, err := fmt.Fprintf(w, "#!/bin/sh\n# Generated by %s on %s\nset -eu\n", appName, now.UTC().Format(auditLayout))
+	return &Script{w: w}, err
+}
+
+// Remove adds removal of the obsolete package superseded by the newer one, if any.
+func (s *Script) Remove(key, path, supersededBy string) error {
+	s.count++
+	reason := "superseded by " + supersededBy
+	if supersededBy == "" {
+		reason = "no version kept"
+	}
+	_, err := fmt.Fprintf(s.w, "\n# %s: %s\nrm -f -- %s\n", shellComment(key), shellComment(reason), shellQuote(path))
+	return err
+}
+
+// RemoveSnapshot adds removal of a stale poudriere snapshot.
+func (s *Script) RemoveSnapshot(path string) error {
+	s.count++
+	_, err := fmt.Fprintf(s.w, "\n# stale poudriere snapshot\nrm -rf -- %s\n", shellQuote(path))
+	return err
+}
+
+// Close writes the script footer.
+func (s *Script) Close() error {
+	_, err := fmt.Fprintf(s.w, "\n# %d removal(s)\n", s.count)
+	return err
+}
+
+// AuditLog - file recording deleted packages as tab-separated "timestamp path size superseded-by" lines
+type AuditLog struct {
+	file *os.File
+	now  func() time.Time
+}
+
+// OpenAuditLog opens the audit log for appending, creating it if necessary.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditLog{file: f, now: time.Now}, nil
+}
+
+// Record appends an entry for a removed file.
+func (a *AuditLog) Record(path string, size int64, supersededBy string) error {
+	_, err := a.file.WriteString(strings.Join([]string{
+		a.now().UTC().Format(auditLayout), path, strconv.FormatInt(size, 10), supersededBy,
+	}, manifestSep) + "\n")
+	return err
+}
+
+// Close -
+func (a *AuditLog) Close() error {
+	return a.file.Close()
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", "''"},
+		{"/pkg/All/bash-5.1.pkg", "'/pkg/All/bash-5.1.pkg'"},
+		{"it's", `'it'\''s'`},
+		{"$(rm -rf /) `x` \"y\"", "'$(rm -rf /) `x` \"y\"'"},
+	}
+	for _, tt := range tests {
+		if got := shellQuote(tt.in); got != tt.want {
+			t.Errorf("shellQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+	if got := shellComment("a\nrm -rf /\tb"); got != "a?rm -rf /?b" {
+		t.Errorf("shellComment() = %q", got)
+	}
+}
+
+func TestScript(t *testing.T) {
+	dir := t.TempDir()
+	odd := filepath.Join(dir, "it's $(odd)\n-1.0.pkg")
+	writeTestFiles(t, dir, "bash-5.1.pkg", "bash-5.2.37.pkg")
+	if err := os.WriteFile(odd, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, ".real_1"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	s, err := NewScript(&buf, time.Date(2025, 7, 7, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Remove("bash", filepath.Join(dir, "bash-5.1.pkg"), filepath.Join(dir, "bash-5.2.37.pkg"))
+	_ = s.Remove("it's $(odd)\n", odd, "new\nline")
+	_ = s.RemoveSnapshot(filepath.Join(dir, ".real_1"))
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	script := buf.String()
+	for _, want := range []string{
+		"#!/bin/sh\n# Generated by " + appName + " on 2025-07-07T12:00:00Z\nset -eu\n",
+		"# bash: superseded by " + filepath.Join(dir, "bash-5.2.37.pkg") + "\nrm -f -- '" + filepath.Join(dir, "bash-5.1.pkg") + "'\n",
+		"superseded by new?line\n",
+		"# 3 removal(s)\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script has no %q:\n%s", want, script)
+		}
+	}
+
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh")
+	}
+	if out, err := exec.Command(sh, "-c", script).CombinedOutput(); err != nil {
+		t.Fatalf("sh: %v: %s", err, out)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || entries[0].Name() != "bash-5.2.37.pkg" {
+		t.Errorf("unexpected files left: %v", entries)
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	for i := 0; i < 2; i++ {
+		a, err := OpenAuditLog(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a.now = func() time.Time { return time.Date(2025, 7, 7, 12, 0, i, 0, time.UTC) }
+		if err = a.Record("/pkg/All/bash-5.1.pkg", 1024, "/pkg/All/bash-5.2.37.pkg"); err != nil {
+			t.Fatal(err)
+		}
+		if err = a.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "2025-07-07T12:00:00Z\t/pkg/All/bash-5.1.pkg\t1024\t/pkg/All/bash-5.2.37.pkg\n" +
+		"2025-07-07T12:00:01Z\t/pkg/All/bash-5.1.pkg\t1024\t/pkg/All/bash-5.2.37.pkg\n"
+	if string(got) != want {
+		t.Errorf("audit log:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// Formats of diagnostics
+const (
+	diagText  = "text"
+	diagJSON  = "json"
+	diagSARIF = "sarif"
+)
+
+var diagFormats = []string{diagText, diagJSON, diagSARIF}
+
+// diagFileError - summary of the error about a file which cannot be read or written
+const diagFileError = "File error"
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// diagPosition returns "file:line,column: " of the diagnostic, or "".
+func diagPosition(diag *hcl.Diagnostic) string {
+	switch {
+	case diag.Subject == nil:
+		return ""
+	case diag.Subject.Start.Line == 0:
+		return diag.Subject.Filename + ": "
+	}
+	return fmt.Sprintf("%s:%d,%d: ", diag.Subject.Filename, diag.Subject.Start.Line, diag.Subject.Start.Column)
+}
+
+// printDiags writes diagnostics as "file:line,column: summary: detail" lines.
+func printDiags(w io.Writer, diags hcl.Diagnostics) {
+	for _, diag := range diags {
+		_, _ = fmt.Fprintf(w, "%s%s: %s\n", diagPosition(diag), diag.Summary, diag.Detail)
+	}
+}
+
+// severity returns the name of the severity of the diagnostic.
+func severity(diag *hcl.Diagnostic) string {
+	if diag.Severity == hcl.DiagError {
+		return "error"
+	}
+	return "warning"
+}
+
+// ruleID returns the identifier of the kind of the diagnostic.
+func ruleID(diag *hcl.Diagnostic) string {
+	switch {
+	case diag.Summary == diagNoLiteral:
+		return "no-literal-argument"
+	case diag.Summary == diagFileError:
+		return "file-error"
+	}
+	if _, ok := diag.Extra.(override); ok {
+		return "overridden-key"
+	}
+	return "parse-error"
+}
+
+// diagPos - position of a diagnostic
+type diagPos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Byte   int `json:"byte"`
+}
+
+// diagRange - source range of a diagnostic; a range without positions refers to the whole file
+type diagRange struct {
+	Filename string   `json:"filename"`
+	Start    *diagPos `json:"start,omitempty"`
+	End      *diagPos `json:"end,omitempty"`
+}
+
+// jsonDiagnostic - diagnostic of -diagnostics json
+type jsonDiagnostic struct {
+	Severity string     `json:"severity"`
+	Rule     string     `json:"rule"`
+	Summary  string     `json:"summary"`
+	Detail   string     `json:"detail,omitempty"`
+	Range    *diagRange `json:"range,omitempty"`
+}
+
+// writeJSONDiags writes diagnostics as {"diagnostics": [...], "error_count": n, "warning_count": n}.
+func writeJSONDiags(w io.Writer, diags hcl.Diagnostics) error {
+	result := struct {
+		Diagnostics  []jsonDiagnostic `json:"diagnostics"`
+		ErrorCount   int              `json:"error_count"`
+		WarningCount int              `json:"warning_count"`
+	}{Diagnostics: []jsonDiagnostic{}}
+
+	for _, diag := range diags {
+		d := jsonDiagnostic{Severity: severity(diag), Rule: ruleID(diag), Summary: diag.Summary, Detail: diag.Detail}
+		if diag.Subject != nil {
+			d.Range = &diagRange{Filename: diag.Subject.Filename}
+			if s := diag.Subject; s.Start.Line > 0 {
+				d.Range.Start = &diagPos{Line: s.Start.Line, Column: s.Start.Column, Byte: s.Start.Byte}
+				d.Range.End = &diagPos{Line: s.End.Line, Column: s.End.Column, Byte: s.End.Byte}
+			}
+		}
+		if diag.Severity == hcl.DiagError {
+			result.ErrorCount++
+		} else {
+			result.WarningCount++
+		}
+		result.Diagnostics = append(result.Diagnostics, d)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
+
+// sarifLog - the subset of SARIF 2.1.0 written by -diagnostics sarif
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name    string      `json:"name"`
+	Version string      `json:"version,omitempty"`
+	Rules   []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+// sarifStdin - the base URI id of stdin, which has no URI of its own
+const sarifStdin = "STDIN"
+
+// sarifArtifact returns the location of the file: a file URI if its path is absolute, otherwise
+// a relative reference; stdin is "stdin" relative to sarifStdin.
+func sarifArtifact(filename string) sarifArtifactLocation {
+	switch {
+	case filename == stdinName:
+		return sarifArtifactLocation{URI: "stdin", URIBaseID: sarifStdin}
+	case filepath.IsAbs(filename):
+		return sarifArtifactLocation{URI: (&url.URL{Scheme: "file", Path: filepath.ToSlash(filename)}).String()}
+	}
+	return sarifArtifactLocation{URI: (&url.URL{Path: filepath.ToSlash(filename)}).String()}
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+// sarifRules - rules of diagnostics by their identifiers
+var sarifRules = []sarifRule{
+	{ID: "parse-error", ShortDescription: sarifMessage{Text: "The configuration cannot be parsed"}},
+	{ID: "file-error", ShortDescription: sarifMessage{Text: "The file cannot be read or written"}},
+	{ID: "overridden-key", ShortDescription: sarifMessage{Text: "A key of merge() is overridden by a later argument"}},
+	{ID: "no-literal-argument", ShortDescription: sarifMessage{Text: "A call is skipped since none of its arguments is a literal"}},
+}
+
+// writeSARIFDiags writes diagnostics as a SARIF 2.1.0 log of a single run.
+func writeSARIFDiags(w io.Writer, diags hcl.Diagnostics) error {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: appName, Version: version, Rules: sarifRules}},
+		Results: []sarifResult{},
+	}
+	for _, diag := range diags {
+		result := sarifResult{RuleID: ruleID(diag), Level: severity(diag), Message: sarifMessage{Text: diag.Summary}}
+		if diag.Detail != "" {
+			result.Message.Text += ": " + diag.Detail
+		}
+		if s := diag.Subject; s != nil {
+			loc := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifact(s.Filename),
+			}}
+			if s.Start.Line > 0 {
+				loc.PhysicalLocation.Region = &sarifRegion{
+					StartLine: s.Start.Line, StartColumn: s.Start.Column, EndLine: s.End.Line, EndColumn: s.End.Column,
+				}
+			}
+			result.Locations = []sarifLocation{loc}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{Schema: sarifSchema, Version: "2.1.0", Runs: []sarifRun{run}})
+}
+
+// writeDiags writes diagnostics in the format, json or sarif.
+func writeDiags(w io.Writer, format string, diags hcl.Diagnostics) error {
+	if format == diagSARIF {
+		return writeSARIFDiags(w, diags)
+	}
+	return writeJSONDiags(w, diags)
+}
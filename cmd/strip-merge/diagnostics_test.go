@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+func TestRewriteBody_NoLiteral(t *testing.T) {
+	var diags hcl.Diagnostics
+	rewriteSourceDiags(t, `a = merge(var.x, local.y)
+b = merge(var.x, { y = 1 })
+c = lookup(var.x, "y")
+`, defaultOptions, &diags)
+
+	if len(diags) != 1 {
+		t.Fatalf("got %d diagnostics, want 1: %s", len(diags), diags.Error())
+	}
+	var text bytes.Buffer
+	printDiags(&text, diags)
+	want := nullHcl + ":1,5: No literal argument: The merge() call is skipped since none of its arguments is a literal.\n"
+	if text.String() != want {
+		t.Errorf("printDiags() = %q, want %q", text.String(), want)
+	}
+}
+
+func TestRunner_Diagnostics(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"a.tf": "a = merge(var.x, var.y)\n",
+		"b.tf": "b = merge(\n",
+	})
+	missing := filepath.Join(dir, "missing.tf")
+
+	var stdout, stderr bytes.Buffer
+	r := &runner{opts: defaultOptions, check: true, stdout: &stdout, stderr: &stderr, format: diagJSON}
+	r.run([]string{dir, missing})
+	if stderr.Len() != 0 {
+		t.Fatalf("diagnostics are written before Flush: %s", stderr.String())
+	}
+	if err := r.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	var result struct {
+		Diagnostics []jsonDiagnostic `json:"diagnostics"`
+		Errors      int              `json:"error_count"`
+		Warnings    int              `json:"warning_count"`
+	}
+	if err := json.Unmarshal(stderr.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Errors < 2 || result.Warnings != 1 || r.failed != 2 {
+		t.Fatalf("errors %d, warnings %d, failed %d:\n%s", result.Errors, result.Warnings, r.failed, stderr.String())
+	}
+	rules := map[string]*diagRange{}
+	for _, d := range result.Diagnostics {
+		rules[d.Rule] = d.Range
+	}
+	if r := rules["no-literal-argument"]; r == nil || r.Filename != filepath.Join(dir, "a.tf") ||
+		r.Start == nil || r.Start.Line != 1 || r.Start.Column != 5 || r.End.Column != 24 {
+		t.Errorf("no-literal-argument range: %+v", r)
+	}
+	if r := rules["parse-error"]; r == nil || r.Filename != filepath.Join(dir, "b.tf") || r.Start == nil {
+		t.Errorf("parse-error range: %+v", r)
+	}
+	if r := rules["file-error"]; r == nil || r.Filename != missing || r.Start != nil {
+		t.Errorf("file-error range: %+v", r)
+	}
+}
+
+func TestWriteSARIFDiags(t *testing.T) {
+	opts := defaultOptions
+	opts.fold = true
+	var diags hcl.Diagnostics
+	rewriteSourceDiags(t, "a = merge({ x = 1 }, { x = 2 })\nb = merge(var.x, var.y)\n", opts, &diags)
+
+	var out bytes.Buffer
+	if err := writeSARIFDiags(&out, diags); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || log.Runs[0].Tool.Driver.Name != appName {
+		t.Fatalf("unexpected log: %s", out.String())
+	}
+
+	var got []string
+	for _, res := range log.Runs[0].Results {
+		region := res.Locations[0].PhysicalLocation.Region
+		got = append(got, fmt.Sprintf("%s %s %s:%d,%d", res.RuleID, res.Level,
+			res.Locations[0].PhysicalLocation.ArtifactLocation.URI, region.StartLine, region.StartColumn))
+	}
+	want := []string{"overridden-key warning " + nullHcl + ":1,13", "no-literal-argument warning " + nullHcl + ":2,5"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("results:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestSARIFArtifact(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     sarifArtifactLocation
+	}{
+		{stdinName, sarifArtifactLocation{URI: "stdin", URIBaseID: sarifStdin}},
+		{"modules/main file.tf", sarifArtifactLocation{URI: "modules/main%20file.tf"}},
+		{"FreeBSD:14/main.tf", sarifArtifactLocation{URI: "./FreeBSD:14/main.tf"}},
+		{"/srv/main.tf", sarifArtifactLocation{URI: "file:///srv/main.tf"}},
+	}
+	for _, tt := range tests {
+		if got := sarifArtifact(tt.filename); got != tt.want {
+			t.Errorf("sarifArtifact(%q) = %+v, want %+v", tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestRunner_SARIFStdin(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	r := &runner{opts: defaultOptions, stdout: &stdout, stderr: &stderr, format: diagSARIF}
+	if err := r.handle(stdinName, []byte("a = merge(var.x, var.y)\n"), 0, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(stderr.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if len(log.Runs) != 1 || len(log.Runs[0].Results) != 1 || len(log.Runs[0].Results[0].Locations) != 1 {
+		t.Fatalf("unexpected log: %s", stderr.String())
+	}
+	loc := log.Runs[0].Results[0].Locations[0].PhysicalLocation
+	if loc.ArtifactLocation != (sarifArtifactLocation{URI: "stdin", URIBaseID: sarifStdin}) || loc.Region == nil || loc.Region.StartLine != 1 {
+		t.Errorf("location = %+v", loc)
+	}
+	if strings.Contains(stderr.String(), stdinName) {
+		t.Errorf("%s in the log: %s", stdinName, stderr.String())
+	}
+}
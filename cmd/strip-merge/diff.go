@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+)
+
+const diffContext = 3 // unchanged lines around changes
+
+// edit - a line kept (' '), removed ('-') or added ('+')
+type edit struct {
+	op   byte
+	line []byte // including its newline, if any
+}
+
+// splitLines splits the text into lines keeping their newlines.
+func splitLines(b []byte) [][]byte {
+	var lines [][]byte
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, '\n') + 1
+		if i == 0 {
+			i = len(b)
+		}
+		lines, b = append(lines, b[:i]), b[i:]
+	}
+	return lines
+}
+
+// lineEdits returns the edits turning a into b: the common prefix and suffix are kept,
+// the lines between them are compared by shortestEdits.
+func lineEdits(a, b [][]byte) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && bytes.Equal(a[prefix], b[prefix]) {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && bytes.Equal(a[len(a)-1-suffix], b[len(b)-1-suffix]) {
+		suffix++
+	}
+
+	var edits []edit
+	for _, line := range a[:prefix] {
+		edits = append(edits, edit{' ', line})
+	}
+	edits = append(edits, shortestEdits(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, edit{' ', line})
+	}
+	return edits
+}
+
+// shortestEdits returns the shortest edits turning x into y found by the Myers O(ND) algorithm,
+// removals preceding additions. It takes O((N+M)·D) time and O(D²) memory for D differing lines.
+func shortestEdits(x, y [][]byte) []edit {
+	n, m := len(x), len(y)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1) // v[offset+k] - the furthest x reached on the diagonal k = x - y
+	var trace [][]int            // trace[d][d+k] - v of the diagonals -d..d after d edits
+
+	for d, done := 0, false; !done; d++ {
+		for k := -d; k <= d && !done; k += 2 {
+			var i int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				i = v[offset+k+1] // down: an addition
+			} else {
+				i = v[offset+k-1] + 1 // right: a removal
+			}
+			j := i - k
+			for i < n && j < m && bytes.Equal(x[i], y[j]) {
+				i, j = i+1, j+1
+			}
+			v[offset+k] = i
+			done = i >= n && j >= m
+		}
+		trace = append(trace, slices.Clone(v[offset-d:offset+d+1]))
+	}
+
+	edits := make([]edit, 0, n+m)
+	i, j := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev, k := trace[d-1], i-j
+		down := k == -d || (k != d && prev[d-1+k-1] < prev[d-1+k+1])
+		var start int // x where the snake ending at (i, j) starts
+		if down {
+			start = prev[d-1+k+1]
+		} else {
+			start = prev[d-1+k-1] + 1
+		}
+		for i > start {
+			i, j = i-1, j-1
+			edits = append(edits, edit{' ', x[i]})
+		}
+		if down {
+			j--
+			edits = append(edits, edit{'+', y[j]})
+		} else {
+			i--
+			edits = append(edits, edit{'-', x[i]})
+		}
+	}
+	for i > 0 {
+		i, j = i-1, j-1
+		edits = append(edits, edit{' ', x[i]})
+	}
+	slices.Reverse(edits)
+	return edits
+}
+
+// unifiedDiff returns the differences between the texts in the unified format, or nil if they are equal.
+func unifiedDiff(oldName, newName string, a, b []byte) []byte {
+	if bytes.Equal(a, b) {
+		return nil
+	}
+	edits := lineEdits(splitLines(a), splitLines(b))
+
+	var changes []int // indexes of edits other than kept lines
+	for k, e := range edits {
+		if e.op != ' ' {
+			changes = append(changes, k)
+		}
+	}
+
+	var out bytes.Buffer
+	_, _ = fmt.Fprintf(&out, "diff -u %s %s\n--- %s\n+++ %s\n", oldName, newName, oldName, newName)
+	oldLine, newLine, next := 1, 1, 0 // line numbers of edits[next]
+	for start := 0; start < len(changes); {
+		end := start
+		for end+1 < len(changes) && changes[end+1]-changes[end] <= 2*diffContext {
+			end++
+		}
+		lo, hi := max(changes[start]-diffContext, 0), min(changes[end]+diffContext, len(edits)-1)
+		for ; next < lo; next++ {
+			oldLine, newLine = oldLine+1, newLine+1 // only kept lines precede a hunk
+		}
+
+		oldCount, newCount := 0, 0
+		for _, e := range edits[lo : hi+1] {
+			if e.op != '+' {
+				oldCount++
+			}
+			if e.op != '-' {
+				newCount++
+			}
+		}
+		_, _ = fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", hunkStart(oldLine, oldCount), oldCount, hunkStart(newLine, newCount), newCount)
+		for _, e := range edits[lo : hi+1] {
+			out.WriteByte(e.op)
+			out.Write(e.line)
+			if !bytes.HasSuffix(e.line, []byte("\n")) {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		oldLine, newLine, next = oldLine+oldCount, newLine+newCount, hi+1
+		start = end + 1
+	}
+	return out.Bytes()
+}
+
+// hunkStart returns the line number of a hunk range: the line before it if the range is empty.
+func hunkStart(line, count int) int {
+	if count == 0 {
+		return line - 1
+	}
+	return line
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	lines := func(n int, change map[int]string) string {
+		var b strings.Builder
+		for i := 1; i <= n; i++ {
+			if s, ok := change[i]; ok {
+				b.WriteString(s)
+			} else {
+				b.WriteString("line " + strings.Repeat("x", i) + "\n")
+			}
+		}
+		return b.String()
+	}
+
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"equal", "a\n", "a\n", ""},
+		{
+			"middle", lines(10, nil), lines(10, map[int]string{5: "five\n"}),
+			"@@ -2,7 +2,7 @@\n line xx\n line xxx\n line xxxx\n-line xxxxx\n+five\n line xxxxxx\n line xxxxxxx\n line xxxxxxxx\n",
+		},
+		{
+			"two hunks", lines(12, nil), lines(12, map[int]string{1: "", 12: "twelve\n"}),
+			"@@ -1,4 +1,3 @@\n-line x\n line xx\n line xxx\n line xxxx\n" +
+				"@@ -9,4 +8,4 @@\n line xxxxxxxxx\n line xxxxxxxxxx\n line xxxxxxxxxxx\n-line xxxxxxxxxxxx\n+twelve\n",
+		},
+		{
+			"insertion", "a\nc\n", "a\nb\nc\n",
+			"@@ -1,2 +1,3 @@\n a\n+b\n c\n",
+		},
+		{
+			"no newline", "a\nb", "a\nc\n",
+			"@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(unifiedDiff("x.orig", "x", []byte(tt.a), []byte(tt.b)))
+			if tt.want != "" {
+				tt.want = "diff -u x.orig x\n--- x.orig\n+++ x\n" + tt.want
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiff_Large(t *testing.T) {
+	var a, b strings.Builder
+	for i := range 20000 {
+		line := fmt.Sprintf("line %d\n", i)
+		a.WriteString(line)
+		switch i {
+		case 100:
+			b.WriteString("changed\n")
+		case 10000:
+		case 19000:
+			b.WriteString(line + "added\n")
+		default:
+			b.WriteString(line)
+		}
+	}
+
+	got := string(unifiedDiff("x.orig", "x", []byte(a.String()), []byte(b.String())))
+	want := "diff -u x.orig x\n--- x.orig\n+++ x\n" +
+		"@@ -98,7 +98,7 @@\n line 97\n line 98\n line 99\n-line 100\n+changed\n line 101\n line 102\n line 103\n" +
+		"@@ -9998,7 +9998,6 @@\n line 9997\n line 9998\n line 9999\n-line 10000\n line 10001\n line 10002\n line 10003\n" +
+		"@@ -18999,6 +18998,7 @@\n line 18998\n line 18999\n line 19000\n+added\n line 19001\n line 19002\n line 19003\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestShortestEdits(t *testing.T) {
+	tests := []struct {
+		a, b, want string
+	}{
+		{"", "", ""},
+		{"abc", "", "-a-b-c"},
+		{"", "abc", "+a+b+c"},
+		{"abcabba", "cbabac", "-a-b c+b a b-b a+c"},
+		{"xaby", "xbay", " x-a b+a y"},
+	}
+	for _, tt := range tests {
+		split := func(s string) [][]byte {
+			var lines [][]byte
+			for _, c := range s {
+				lines = append(lines, []byte(string(c)))
+			}
+			return lines
+		}
+		var got strings.Builder
+		for _, e := range shortestEdits(split(tt.a), split(tt.b)) {
+			got.WriteByte(e.op)
+			got.Write(e.line)
+		}
+		if got.String() != tt.want {
+			t.Errorf("shortestEdits(%s, %s) = %s, want %s", tt.a, tt.b, got.String(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// sourceExtensions - files processed when found in a directory
+var sourceExtensions = append([]string{".hcl", ".tf"}, jsonExtensions...)
+
+// errParse - the file is not a valid HCL configuration
+var errParse = errors.New("parse error")
+
+// rewrite parses the configuration, rewrites its merge(...) attributes and formats the result.
+// It returns the number of attributes rewritten, parse errors or warnings about the rewritten attributes.
+func rewrite(name string, src []byte, opts options) ([]byte, int, hcl.Diagnostics) {
+	if isJSON(name, src) {
+		return rewriteJSON(name, src, opts)
+	}
+
+	// syntax AST - read only
+	syntaxFile, diags := hclsyntax.ParseConfig(src, name, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, 0, diags
+	}
+
+	// write AST - write only
+	writeFile, diags := hclwrite.ParseConfig(src, name, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, 0, diags
+	}
+
+	count, diags := rewriteBody(src, syntaxFile.Body.(*hclsyntax.Body), writeFile.Body(), nil, opts)
+	return hclwrite.Format(writeFile.Bytes()), count, diags
+}
+
+// sourceFiles returns the files under the directory having one of sourceExtensions in lexical order.
+// Hidden directories such as .terraform, .terragrunt-cache or .git are skipped.
+func sourceFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		for _, ext := range sourceExtensions {
+			if entry.Type().IsRegular() && strings.HasSuffix(path, ext) {
+				files = append(files, path)
+				break
+			}
+		}
+		return nil
+	})
+	return files, err
+}
+
+// writeFile replaces the file with the data keeping its permissions: the data is written
+// to a temporary file in the same directory which is then renamed.
+func writeFile(path string, data []byte, perm fs.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	// nolint:errcheck
+	defer os.Remove(tmp)
+
+	if _, err = f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Chmod(perm); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// runner processes files like gofmt does: the result is printed, written back, or compared.
+type runner struct {
+	opts    options
+	resolve bool // -resolve: inline literal locals and variable defaults of the module directory
+	write   bool // -w: rewrite files in place
+	diff    bool // -d: print diffs instead of results
+	check   bool // -check: list files which would change instead of printing results
+	stdout  io.Writer
+	stderr  io.Writer
+	format  string // -diagnostics: text is printed at once, other formats are collected
+
+	changed int // files which differ from their results
+	failed  int // files which could not be processed
+	diags   hcl.Diagnostics
+	modules map[string]map[string]definitions // -resolve: definitions of the files of every module directory, read once
+}
+
+// structured reports whether diagnostics are collected to be written by Flush.
+func (r *runner) structured() bool {
+	return r.format != "" && r.format != diagText
+}
+
+// report prints or collects diagnostics of a file; errors are preceded by the message in the text format.
+func (r *runner) report(msg string, diags hcl.Diagnostics) {
+	switch {
+	case r.structured():
+		r.diags = append(r.diags, diags...)
+	case diags.HasErrors():
+		_, _ = fmt.Fprintf(r.stderr, "%s: %d diagnostic(s):\n\n", msg, len(diags))
+		printDiags(r.stderr, diags)
+	default:
+		printDiags(r.stderr, diags)
+	}
+}
+
+// fail reports the error of a file which cannot be processed.
+func (r *runner) fail(path string, err error) {
+	r.failed++
+	if !r.structured() {
+		_, _ = fmt.Fprintln(r.stderr, "ERROR:", err)
+		return
+	}
+	r.diags = append(r.diags, &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  diagFileError,
+		Detail:   err.Error(),
+		Subject:  &hcl.Range{Filename: path},
+	})
+}
+
+// Flush writes the collected diagnostics unless they are printed as text.
+func (r *runner) Flush() error {
+	if !r.structured() {
+		return nil
+	}
+	return writeDiags(r.stderr, r.format, r.diags)
+}
+
+// handle processes the source of a file; the result of the file without merge(...) attributes to rewrite
+// is the file itself unless reformat is set. Read-only sources such as stdin cannot be written back.
+func (r *runner) handle(path string, src []byte, perm fs.FileMode, reformat bool) error {
+	opts := r.opts
+	if r.resolve && path == stdinName {
+		opts.defs = definitions{}
+		opts.defs.collect(path, src)
+	} else if r.resolve {
+		dir := filepath.Dir(path)
+		if r.modules == nil {
+			r.modules = map[string]map[string]definitions{}
+		}
+		if _, ok := r.modules[dir]; !ok {
+			r.modules[dir] = dirDefinitions(dir)
+		}
+		opts.defs = moduleDefinitions(path, src, r.modules[dir])
+	}
+
+	res, count, diags := rewrite(path, src, opts)
+	r.report("Failed to parse "+path, diags)
+	if diags.HasErrors() {
+		return errParse
+	}
+	if count == 0 && !reformat {
+		res = src
+	}
+
+	changed := !bytes.Equal(src, res)
+	if changed {
+		r.changed++
+	}
+
+	if r.diff {
+		_, _ = r.stdout.Write(unifiedDiff(path+".orig", path, src, res))
+	} else if r.check && changed {
+		_, _ = fmt.Fprintln(r.stdout, path)
+	}
+	if r.write && changed {
+		return writeFile(path, res, perm)
+	}
+	if !r.write && !r.diff && !r.check {
+		_, _ = r.stdout.Write(res)
+	}
+	return nil
+}
+
+// file processes a file given as an argument or found in a directory.
+func (r *runner) file(path string) {
+	err := func() error {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return r.handle(path, src, info.Mode().Perm(), false)
+	}()
+	if errors.Is(err, errParse) {
+		r.failed++
+	} else if err != nil {
+		r.fail(path, err)
+	}
+}
+
+// run processes files and directories given as arguments.
+func (r *runner) run(args []string) {
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			r.fail(arg, err)
+			continue
+		}
+		if !info.IsDir() {
+			r.file(arg)
+			continue
+		}
+		files, err := sourceFiles(arg)
+		if err != nil {
+			r.fail(arg, err)
+		}
+		for _, path := range files {
+			r.file(path)
+		}
+	}
+}
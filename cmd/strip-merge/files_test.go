@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestSourceFiles(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"terragrunt.hcl":                 "",
+		"b/main.tf":                      "",
+		"b/README.md":                    "",
+		"b/main.tf.json":                 "",
+		"b/package.json":                 "",
+		".terraform/modules/x/main.tf":   "",
+		"c/.terragrunt-cache/x/main.hcl": "",
+	})
+
+	files, err := sourceFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "b/main.tf"), filepath.Join(dir, "b/main.tf.json"), filepath.Join(dir, "terragrunt.hcl")}
+	if !slices.Equal(files, want) {
+		t.Errorf("sourceFiles() = %v, want %v", files, want)
+	}
+}
+
+func TestRunner(t *testing.T) {
+	const (
+		merged   = "inputs = merge(var.a, {\n  x = 1\n})\n"
+		stripped = "inputs = {\n  x = 1\n}\n"
+		plain    = "a   =   1\n" // not formatted, but nothing to rewrite
+	)
+	dir := writeTree(t, map[string]string{"a/terragrunt.hcl": merged, "b/main.tf": plain})
+	changed := filepath.Join(dir, "a/terragrunt.hcl")
+
+	var stdout, stderr bytes.Buffer
+	r := &runner{opts: defaultOptions, check: true, stdout: &stdout, stderr: &stderr}
+	r.run([]string{dir})
+	if r.changed != 1 || r.failed != 0 || stdout.String() != changed+"\n" {
+		t.Errorf("-check: changed %d, failed %d, output %q", r.changed, r.failed, stdout.String())
+	}
+
+	stdout.Reset()
+	r = &runner{opts: defaultOptions, diff: true, stdout: &stdout, stderr: &stderr}
+	r.run([]string{dir})
+	if !strings.Contains(stdout.String(), "\n-inputs = merge(var.a, {\n+inputs = {\n") {
+		t.Errorf("-d: output %q", stdout.String())
+	}
+
+	stdout.Reset()
+	r = &runner{opts: defaultOptions, write: true, stdout: &stdout, stderr: &stderr}
+	r.run([]string{changed, filepath.Join(dir, "b")})
+	if r.changed != 1 || stdout.Len() != 0 {
+		t.Errorf("-w: changed %d, output %q", r.changed, stdout.String())
+	}
+	for path, want := range map[string]string{changed: stripped, filepath.Join(dir, "b/main.tf"): plain} {
+		if b, err := os.ReadFile(path); err != nil || string(b) != want {
+			t.Errorf("%s = %q, %v; want %q", path, b, err, want)
+		}
+	}
+	if info, err := os.Stat(changed); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("permissions of the written file are not kept: %v", err)
+	}
+
+	r = &runner{opts: defaultOptions, stdout: &stdout, stderr: &stderr}
+	r.run([]string{filepath.Join(dir, "missing.tf")})
+	if r.failed != 1 {
+		t.Errorf("missing file: failed %d, want 1", r.failed)
+	}
+}
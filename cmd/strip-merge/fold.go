@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// segment - tokens of an object item: leading comments, the item itself and its trailing comment
+type segment struct {
+	tokens hclsyntax.Tokens
+	item   *hclsyntax.ObjectConsItem // nil for comments after the last item
+	end    int                       // byte offset of the end of the item value
+}
+
+// objectSegments splits the tokens between the braces of an object literal into segments of its items.
+func objectSegments(tokens hclsyntax.Tokens, obj *hclsyntax.ObjectConsExpr) []segment {
+	var body hclsyntax.Tokens // tokens between the braces
+	for _, tok := range tokens {
+		if tok.Range.Start.Byte >= obj.OpenRange.End.Byte && tok.Range.End.Byte < obj.SrcRange.End.Byte {
+			body = append(body, tok)
+		}
+	}
+	if len(body) > 0 && body[0].Type == hclsyntax.TokenNewline {
+		body = body[1:]
+	}
+
+	var segments []segment
+	pos := 0
+	for i := range obj.Items {
+		item := &obj.Items[i]
+		end := item.ValueExpr.Range().End
+		start := pos
+		for pos < len(body) && body[pos].Range.Start.Byte < end.Byte {
+			pos++
+		}
+	trailing:
+		for pos < len(body) {
+			switch tok := body[pos]; {
+			case tok.Type == hclsyntax.TokenComma:
+			case tok.Type == hclsyntax.TokenComment && tok.Range.Start.Line == end.Line:
+				if bytes.HasSuffix(tok.Bytes, []byte("\n")) {
+					pos++
+					break trailing
+				}
+			case tok.Type == hclsyntax.TokenNewline:
+				pos++
+				break trailing
+			default:
+				break trailing
+			}
+			pos++
+		}
+		segments = append(segments, segment{tokens: body[start:pos], item: item, end: end.Byte})
+	}
+
+	for _, tok := range body[pos:] {
+		if tok.Type == hclsyntax.TokenComment {
+			segments = append(segments, segment{tokens: body[pos:]})
+			break
+		}
+	}
+	return segments
+}
+
+// override - Extra of an "Overridden key" diagnostic
+type override struct {
+	key string
+	by  hcl.Range // the key of the winning item
+}
+
+// detail returns the Detail of the diagnostic of the overridden key at the subject.
+func (o override) detail(subject hcl.Range) string {
+	if o.by.Filename != subject.Filename {
+		return fmt.Sprintf("Key %q is overridden at %s:%d.", o.key, o.by.Filename, o.by.Start.Line)
+	}
+	return fmt.Sprintf("Key %q is overridden at line %d.", o.key, o.by.Start.Line)
+}
+
+// foldMerge folds a merge(...) call expression whose arguments are all object literals with constant keys
+// into a single object literal as merge() evaluates it: the rightmost item of a key wins. Surviving items
+// keep their tokens including comments. Overridden items are reported as warnings.
+// It returns false if the expression cannot be folded.
+func foldMerge(src []byte, expr hclsyntax.Expression) (hclwrite.Tokens, hcl.Diagnostics, bool) {
+	call, ok := expr.(*hclsyntax.FunctionCallExpr)
+	if !ok || call.Name != "merge" || len(call.Args) == 0 {
+		return nil, nil, false
+	}
+
+	var objects []*hclsyntax.ObjectConsExpr
+	last := map[string]*hclsyntax.ObjectConsItem{} // key -> the winning item
+	for _, arg := range call.Args {
+		obj, ok := arg.(*hclsyntax.ObjectConsExpr)
+		if !ok {
+			return nil, nil, false
+		}
+		for i := range obj.Items {
+			k := objectKey(src, obj.Items[i].KeyExpr)
+			if strings.HasPrefix(k, "\x00") {
+				return nil, nil, false // a key known only at evaluation may override any other
+			}
+			last[k] = &obj.Items[i]
+		}
+		objects = append(objects, obj)
+	}
+
+	r := call.Range()
+	tokens, diags := hclsyntax.LexExpression(rangeBytes(src, r), r.Filename, r.Start)
+	if diags.HasErrors() {
+		return nil, nil, false
+	}
+
+	result := hclwrite.Tokens{
+		{Type: hclsyntax.TokenOBrace, Bytes: []byte("{")},
+		{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")},
+	}
+	for _, obj := range objects {
+		for _, seg := range objectSegments(tokens, obj) {
+			if seg.item != nil {
+				k := objectKey(src, seg.item.KeyExpr)
+				if winner := last[k]; winner != seg.item {
+					subject, o := seg.item.KeyExpr.Range(), override{key: k, by: winner.KeyExpr.Range()}
+					diags = append(diags, &hcl.Diagnostic{
+						Severity: hcl.DiagWarning,
+						Summary:  "Overridden key",
+						Detail:   o.detail(subject),
+						Subject:  &subject,
+						Extra:    o,
+					})
+					continue
+				}
+			}
+			result = append(result, seg.writeTokens()...)
+		}
+	}
+	return append(result, &hclwrite.Token{Type: hclsyntax.TokenCBrace, Bytes: []byte("}")}), diags, true
+}
+
+// writeTokens converts the segment into hclwrite tokens keeping the spaces between them,
+// without the comma separating the item from the next one, ending it with a newline.
+func (seg *segment) writeTokens() hclwrite.Tokens {
+	var result hclwrite.Tokens
+	var prev *hclsyntax.Token
+	for i := range seg.tokens {
+		tok := &seg.tokens[i]
+		if tok.Type == hclsyntax.TokenComma && seg.item != nil && tok.Range.Start.Byte >= seg.end {
+			continue
+		}
+		spaces := 0
+		if prev != nil && prev.Range.End.Line == tok.Range.Start.Line {
+			spaces = tok.Range.Start.Byte - prev.Range.End.Byte
+		}
+		result = append(result, &hclwrite.Token{Type: tok.Type, Bytes: tok.Bytes, SpacesBefore: spaces})
+		prev = tok
+	}
+	if n := len(result); n == 0 || !bytes.HasSuffix(result[n-1].Bytes, []byte("\n")) {
+		result = append(result, &hclwrite.Token{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")})
+	}
+	return result
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+func TestFoldMerge(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       string
+		want      string
+		overrides []int // lines of overridden keys
+	}{
+		{
+			name:      "rightmost wins",
+			src:       "inputs = merge({ a = 1, b = 2 }, { a = 3 })\n",
+			want:      "inputs = {\n  b = 2\n  a = 3\n}\n",
+			overrides: []int{1},
+		},
+		{
+			name: "comments",
+			src: `inputs = merge({
+  # about a
+  a = 1 # old
+  b = [1,
+  2], // list
+  /* c */ c = 3,
+
+  # dangling
+}, {
+  a = "x" # new
+  "d" = 4
+})
+`,
+			want: `inputs = {
+  b = [1,
+  2] // list
+  /* c */ c = 3
+
+  # dangling
+  a   = "x" # new
+  "d" = 4
+}
+`,
+			overrides: []int{3},
+		},
+		{
+			name: "single object",
+			src:  "inputs = merge({ a = 1 })\n",
+			want: "inputs = {\n  a = 1\n}\n",
+		},
+		{
+			name: "non-literal argument",
+			src:  "inputs = merge(var.x, { a = 1 }, { b = 2 })\n",
+			want: "inputs = { a = 1 }\n",
+		},
+		{
+			name: "non-constant key",
+			src:  "inputs = merge({ (local.k) = 1 }, { k = 2 })\n",
+			want: "inputs = { (local.k) = 1 }\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, count, diags := rewriteFoldSource(t, tt.src)
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+			if count != 1 {
+				t.Errorf("rewriteBody() = %d, want 1", count)
+			}
+			var lines []int
+			for _, diag := range diags {
+				if diag.Severity != hcl.DiagWarning {
+					t.Errorf("unexpected diagnostic: %s", diag.Error())
+				}
+				lines = append(lines, diag.Subject.Start.Line)
+			}
+			if len(lines) != len(tt.overrides) || (len(lines) > 0 && lines[0] != tt.overrides[0]) {
+				t.Errorf("overridden keys at lines %v, want %v", lines, tt.overrides)
+			}
+		})
+	}
+}
+
+func rewriteFoldSource(t *testing.T, src string) (string, int, hcl.Diagnostics) {
+	t.Helper()
+
+	var diags hcl.Diagnostics
+	got, count := rewriteSourceDiags(t, src, options{strategy: strategyLargest, fold: true, funcs: defaultOptions.funcs}, &diags)
+	return got, count, diags
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// funcRule - how calls of a function are reduced
+type funcRule struct {
+	wrapper bool // the call is replaced with its first argument if that is reduced
+	reduce  func(src []byte, call *hclsyntax.FunctionCallExpr, opts options) ([]byte, hcl.Diagnostics, bool)
+	literal func(hclsyntax.Expression) bool // arguments the call can be reduced to
+}
+
+// funcRules - registry of functions whose calls can be reduced, selected with -funcs
+var funcRules = map[string]funcRule{
+	"merge":        {reduce: reduceMergeCall, literal: objectLiterals.literal},
+	"concat":       {reduce: reduceConcatCall, literal: tupleLiterals.literal},
+	"coalesce":     {reduce: reduceFirstCall(isEmptyValue, isPresentValue), literal: isPresentValue},
+	"coalescelist": {reduce: reduceFirstCall(isEmptyTuple, isPresentTuple), literal: isPresentTuple},
+	"tomap":        {wrapper: true},
+	"try":          {wrapper: true},
+}
+
+// selectedRule returns the rule of the function if calls of it are reduced: the function is one of funcs,
+// or a wrapper, which is always looked through to the call it wraps.
+func selectedRule(name string, funcs []string) (funcRule, bool) {
+	rule, ok := funcRules[name]
+	return rule, ok && (rule.wrapper || slices.Contains(funcs, name))
+}
+
+// reduceCall returns the source text replacing the expression if it is a call of one of the functions
+// of the options, see selectedRule. Wrappers such as tomap(merge(...)) or try(merge(...), {}) are replaced
+// with their first argument reduced, or kept around it if non-literal arguments are kept.
+// It returns false if the expression is not reduced.
+func reduceCall(src []byte, expr hclsyntax.Expression, opts options) ([]byte, hcl.Diagnostics, bool) {
+	call, ok := expr.(*hclsyntax.FunctionCallExpr)
+	if !ok {
+		return nil, nil, false
+	}
+	rule, ok := selectedRule(call.Name, opts.funcs)
+	if !ok {
+		return nil, nil, false
+	}
+	if !rule.wrapper {
+		return rule.reduce(src, call, opts)
+	}
+	if len(call.Args) == 0 {
+		return nil, nil, false
+	}
+	result, diags, ok := reduceCall(src, call.Args[0], opts)
+	if !ok || !opts.keepNonLiteral {
+		return result, diags, ok
+	}
+	args := [][]byte{result}
+	for _, arg := range call.Args[1:] {
+		args = append(args, rangeBytes(src, arg.Range()))
+	}
+	return callBytes(call, args), diags, true
+}
+
+// diagNoLiteral - summary of the warning about a call not reduced for lack of literal arguments
+const diagNoLiteral = "No literal argument"
+
+// unreducedCall returns a warning if the expression is a call of one of the functions of the options,
+// possibly inside wrappers, which is not reduced since none of its arguments is a literal, or nil.
+func unreducedCall(expr hclsyntax.Expression, opts options) *hcl.Diagnostic {
+	call, ok := expr.(*hclsyntax.FunctionCallExpr)
+	if !ok {
+		return nil
+	}
+	rule, ok := selectedRule(call.Name, opts.funcs)
+	if !ok {
+		return nil
+	}
+	if rule.wrapper {
+		if len(call.Args) == 0 {
+			return nil
+		}
+		return unreducedCall(call.Args[0], opts)
+	}
+	if slices.ContainsFunc(call.Args, rule.literal) {
+		return nil
+	}
+	subject := call.Range()
+	return &hcl.Diagnostic{
+		Severity: hcl.DiagWarning,
+		Summary:  diagNoLiteral,
+		Detail:   fmt.Sprintf("The %s() call is skipped since none of its arguments is a literal.", call.Name),
+		Subject:  &subject,
+	}
+}
+
+// reduceMergeCall folds merge() if requested and possible, otherwise selects its object literal arguments.
+func reduceMergeCall(src []byte, call *hclsyntax.FunctionCallExpr, opts options) ([]byte, hcl.Diagnostics, bool) {
+	if opts.fold {
+		if tokens, diags, ok := foldMerge(src, call); ok {
+			return tokens.Bytes(), diags, true
+		}
+	}
+	result, ok := reduceLiterals(src, call, objectLiterals, opts)
+	return result, nil, ok
+}
+
+// reduceConcatCall folds concat() of tuple literals only into a single tuple if requested,
+// otherwise selects its tuple literal arguments.
+func reduceConcatCall(src []byte, call *hclsyntax.FunctionCallExpr, opts options) ([]byte, hcl.Diagnostics, bool) {
+	if opts.fold && len(call.Args) > 0 && !slices.ContainsFunc(call.Args, func(arg hclsyntax.Expression) bool {
+		return !tupleLiterals.literal(arg)
+	}) {
+		return concatTuple(src, call.Args), nil, true
+	}
+	result, ok := reduceLiterals(src, call, tupleLiterals, opts)
+	return result, nil, ok
+}
+
+// reduceFirstCall returns the rule of a function returning its first argument which is not empty,
+// such as coalesce() or coalescelist(). Arguments known to be empty are dropped, and so are
+// the arguments after the first one known to be present, which is the result if non-literal arguments
+// are dropped, or it is the first argument left.
+func reduceFirstCall(empty, present func(hclsyntax.Expression) bool) func([]byte, *hclsyntax.FunctionCallExpr, options) ([]byte, hcl.Diagnostics, bool) {
+	return func(src []byte, call *hclsyntax.FunctionCallExpr, opts options) ([]byte, hcl.Diagnostics, bool) {
+		var args []hclsyntax.Expression
+		for _, arg := range call.Args {
+			if empty(arg) {
+				continue
+			}
+			if present(arg) && (!opts.keepNonLiteral || len(args) == 0) {
+				return rangeBytes(src, arg.Range()), nil, true
+			}
+			if args = append(args, arg); present(arg) {
+				break
+			}
+		}
+		if !opts.keepNonLiteral || len(args) == 0 || len(args) == len(call.Args) {
+			return nil, nil, false // no literal to select, or nothing to reduce
+		}
+		if len(args) == 1 {
+			return rangeBytes(src, args[0].Range()), nil, true
+		}
+
+		argBytes := make([][]byte, len(args))
+		for i, arg := range args {
+			argBytes[i] = rangeBytes(src, arg.Range())
+		}
+		return callBytes(call, argBytes), nil, true
+	}
+}
+
+// constantValue returns the value of an expression not depending on anything, or false.
+func constantValue(expr hclsyntax.Expression) (cty.Value, bool) {
+	v, diags := expr.Value(nil)
+	return v, !diags.HasErrors() && v.IsWhollyKnown()
+}
+
+// isEmptyValue reports whether coalesce() skips the argument: it is null or an empty string.
+func isEmptyValue(expr hclsyntax.Expression) bool {
+	v, ok := constantValue(expr)
+	return ok && (v.IsNull() || (v.Type() == cty.String && v.AsString() == ""))
+}
+
+// isPresentValue reports whether coalesce() returns the argument: it is a collection literal or a constant
+// which is not empty.
+func isPresentValue(expr hclsyntax.Expression) bool {
+	switch expr.(type) {
+	case *hclsyntax.ObjectConsExpr, *hclsyntax.TupleConsExpr:
+		return true
+	}
+	_, ok := constantValue(expr)
+	return ok && !isEmptyValue(expr)
+}
+
+// isEmptyTuple reports whether coalescelist() skips the argument: it is an empty tuple literal.
+func isEmptyTuple(expr hclsyntax.Expression) bool {
+	tuple, ok := expr.(*hclsyntax.TupleConsExpr)
+	return ok && len(tuple.Exprs) == 0
+}
+
+// isPresentTuple reports whether coalescelist() returns the argument: it is a tuple literal which is not empty.
+func isPresentTuple(expr hclsyntax.Expression) bool {
+	tuple, ok := expr.(*hclsyntax.TupleConsExpr)
+	return ok && len(tuple.Exprs) > 0
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReduceCall(t *testing.T) {
+	all := []string{"merge", "concat", "coalesce", "coalescelist", "tomap", "try"}
+	drop := options{strategy: strategyLargest, funcs: all}
+	keep := options{strategy: strategyLargest, funcs: all, keepNonLiteral: true}
+	fold := options{strategy: strategyLargest, funcs: all, fold: true}
+
+	tests := []struct {
+		src  string
+		opts options
+		want string // "" if not reduced
+	}{
+		{`merge(var.x, { k = 1 })`, defaultOptions, `{ k = 1 }`},
+		{`concat(var.l, ["a"])`, defaultOptions, ``},
+		{`tomap(merge(var.x, { k = 1 }))`, defaultOptions, `{ k = 1 }`},
+		{`try(merge(var.x, { k = 1 }), {})`, defaultOptions, `{ k = 1 }`},
+		{`try(concat(var.l, ["a"]), [])`, defaultOptions, ``},
+
+		{`tomap(merge(var.x, { k = 1 }))`, drop, `{ k = 1 }`},
+		{`try(merge(var.x, { k = 1 }), {})`, drop, `{ k = 1 }`},
+		{`try(var.x, {})`, drop, ``},
+		{`concat(var.l, ["a"], ["b", "c"])`, drop, `["b", "c"]`},
+		{`concat(["a"], ["b"])`, fold, `["a", "b"]`},
+		{`concat(var.l, ["a"])`, fold, `["a"]`},
+		{`coalesce(null, "", var.x, "dflt", var.y)`, drop, `"dflt"`},
+		{`coalesce(var.x, var.y)`, drop, ``},
+		{`coalescelist([], var.l, ["x"])`, drop, `["x"]`},
+
+		{`try(merge(var.x, { k = 1 }, { j = 2 }), {})`, keep, `try(merge(var.x, { k = 1 }), {})`},
+		{`concat(var.l, ["a"], ["b", "c"])`, keep, `concat(var.l, ["b", "c"])`},
+		{`coalesce(null, "", var.x, "dflt", var.y)`, keep, `coalesce(var.x, "dflt")`},
+		{`coalesce(null, { a = 1 }, var.x)`, keep, `{ a = 1 }`},
+		{`coalesce(null, var.x)`, keep, `var.x`},
+		{`coalesce(var.x, var.y)`, keep, ``},
+		{`coalescelist([], var.l, ["x"], var.m)`, keep, `coalescelist(var.l, ["x"])`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src+"/"+strings.Join(tt.opts.funcs, ","), func(t *testing.T) {
+			attr, b := parseSingleAttribute(t, "a = "+tt.src)
+			got, _, ok := reduceCall(b, attr.Expr, tt.opts)
+			if !ok {
+				got = nil
+			}
+			if string(got) != tt.want {
+				t.Errorf("reduceCall() = %q, %v; want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ go 1.26
 require (
 	github.com/hashicorp/hcl/v2 v2.24.0
 	github.com/omilevskyi/go v0.1.1
+	github.com/zclconf/go-cty v1.19.0
 )
 
 require (
@@ -13,7 +14,6 @@ require (
 	github.com/apparentlymart/go-textseg/v17 v17.0.1 // indirect
 	github.com/google/go-cmp v0.7.0 // indirect
 	github.com/mitchellh/go-wordwrap v1.0.1 // indirect
-	github.com/zclconf/go-cty v1.19.0 // indirect
 	golang.org/x/mod v0.38.0 // indirect
 	golang.org/x/sync v0.22.0 // indirect
 	golang.org/x/text v0.40.0 // indirect
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	hcljson "github.com/hashicorp/hcl/v2/json"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// jsonExtensions - files in the HCL JSON syntax
+var jsonExtensions = []string{".tf.json", ".hcl.json"}
+
+// isJSON reports whether the configuration is in the HCL JSON syntax: by the extension of its file,
+// or by its content, since a configuration in the native syntax never starts with "{".
+func isJSON(name string, src []byte) bool {
+	for _, ext := range jsonExtensions {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return bytes.HasPrefix(bytes.TrimLeft(src, " \t\r\n"), []byte("{"))
+}
+
+// jsonString - a string value, not a property name, of a JSON document
+type jsonString struct {
+	start, end int // byte offsets of the quoted string in the document
+	value      string
+	path       []string // names of the properties enclosing the value; arrays add nothing
+}
+
+// jsonStrings returns the string values of the JSON document in their order.
+func jsonStrings(src []byte) ([]jsonString, error) {
+	dec := json.NewDecoder(bytes.NewReader(src))
+	dec.UseNumber()
+
+	type level struct { // an open object or array
+		object, name bool   // whether a property name is next
+		key          string // the last property name
+	}
+	var stack []level
+	valueDone := func() { // the next string of an object is a property name again
+		if n := len(stack); n > 0 && stack[n-1].object {
+			stack[n-1].name = true
+		}
+	}
+
+	var strs []jsonString
+	for {
+		offset := dec.InputOffset()
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return strs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok := tok.(type) {
+		case json.Delim:
+			switch tok {
+			case '{', '[':
+				if n := len(stack); n > 0 && stack[n-1].object {
+					stack[n-1].name = false
+				}
+				stack = append(stack, level{object: tok == '{', name: tok == '{'})
+			default:
+				stack = stack[:len(stack)-1]
+				valueDone()
+			}
+		default:
+			if n := len(stack); n > 0 && stack[n-1].name {
+				stack[n-1].name, stack[n-1].key = false, tok.(string)
+				continue
+			}
+			if s, ok := tok.(string); ok {
+				var path []string
+				for _, l := range stack {
+					if l.object {
+						path = append(path, l.key)
+					}
+				}
+				// only spaces, colons and commas may precede the opening quote
+				start := int(offset) + bytes.IndexByte(src[offset:], '"')
+				strs = append(strs, jsonString{start: start, end: int(dec.InputOffset()), value: s, path: path})
+			}
+			valueDone()
+		}
+	}
+}
+
+// position returns the position of the byte offset in the document.
+func position(src []byte, offset int) hcl.Pos {
+	line := bytes.Count(src[:offset], []byte("\n"))
+	return hcl.Pos{Line: line + 1, Column: offset - bytes.LastIndexByte(src[:offset], '\n'), Byte: offset}
+}
+
+// stringRemapper returns the mapping of ranges of the decoded value of the JSON string to the document:
+// a piece follows every escape and every invalid UTF-8 byte, whose decoded lengths differ from their sources.
+func stringRemapper(name string, src []byte, str jsonString) remapper {
+	from := str.start + 1 // after the quote
+	m := remapper{{start: 0, src: src, pos: position(src, from), from: from, file: name}}
+	decoded := 0
+	for i := from; i < str.end-1; {
+		raw, n := 1, 1
+		switch r, size := utf8.DecodeRune(src[i:]); {
+		case src[i] == '\\' && src[i+1] == 'u':
+			raw, n = 6, utf8.RuneLen(unicode.ReplacementChar)
+			r1, _ := strconv.ParseUint(string(src[i+2:i+6]), 16, 16)
+			if !utf16.IsSurrogate(rune(r1)) {
+				n = utf8.RuneLen(rune(r1))
+			} else if i+12 <= len(src) && src[i+6] == '\\' && src[i+7] == 'u' {
+				r2, _ := strconv.ParseUint(string(src[i+8:i+12]), 16, 16)
+				if r := utf16.DecodeRune(rune(r1), rune(r2)); r != unicode.ReplacementChar {
+					raw, n = 12, utf8.RuneLen(r)
+				}
+			}
+		case src[i] == '\\':
+			raw = 2
+		case r == utf8.RuneError && size == 1:
+			n = utf8.RuneLen(unicode.ReplacementChar)
+		default:
+			raw, n = size, size
+		}
+		i, decoded = i+raw, decoded+n
+		if raw != n {
+			last := m[len(m)-1]
+			m = append(m, piece{start: decoded, src: src, pos: posAfter(last.pos, src, i), from: i, file: name})
+		}
+	}
+	return m
+}
+
+// literalOnly reports whether the strings of the path are never templates in the JSON syntax:
+// comments of "//" properties, and the default, description and type of variables.
+func literalOnly(path []string) bool {
+	if slices.Contains(path, "//") {
+		return true
+	}
+	return len(path) >= 3 && path[0] == "variable" && slices.Contains([]string{"default", "description", "type"}, path[2])
+}
+
+// rewriteJSON rewrites merge(...) and other function calls of the configuration in the HCL JSON syntax:
+// every string value such as "${merge(...)}" is replaced with the JSON value of the result, see jsonValue. The rest of the document is kept, however
+// it is indented once anything is rewritten.
+func rewriteJSON(name string, src []byte, opts options) ([]byte, int, hcl.Diagnostics) {
+	if _, diags := hcljson.Parse(src, name); diags.HasErrors() {
+		return nil, 0, diags
+	}
+	strs, err := jsonStrings(src)
+	if err != nil {
+		return nil, 0, hcl.Diagnostics{{Severity: hcl.DiagError, Summary: "Invalid JSON", Detail: err.Error()}}
+	}
+
+	var out bytes.Buffer
+	var diags hcl.Diagnostics
+	count, last := 0, 0
+	for _, str := range strs {
+		if !strings.HasPrefix(str.value, "${") || literalOnly(str.path) || !opts.selector.Selected(str.path) {
+			continue
+		}
+		// the template has no file name, so that only its ranges are mapped back through the escapes of the string
+		template, parseDiags := hclsyntax.ParseTemplate([]byte(str.value), "", hcl.Pos{Line: 1, Column: 1})
+		wrap, ok := template.(*hclsyntax.TemplateWrapExpr)
+		if parseDiags.HasErrors() || !ok {
+			continue
+		}
+		quoted := stringRemapper(name, src, str)
+		mapTemplate := func(r hcl.Range) hcl.Range {
+			if r.Filename != "" { // inlined from a definition
+				return r
+			}
+			return quoted.Range(r)
+		}
+
+		tmpl, expr, m := []byte(str.value), wrap.Wrapped, remapper(nil)
+		if opts.defs != nil {
+			tmpl, expr, m = inlineReferences(tmpl, expr, opts.defs, opts.funcs)
+		}
+		result, callDiags, ok := reduceCall(tmpl, expr, opts)
+		if !ok {
+			if diag := unreducedCall(expr, opts); diag != nil {
+				if m != nil {
+					m.Diagnostics(hcl.Diagnostics{diag})
+				}
+				mapDiagnostics(hcl.Diagnostics{diag}, mapTemplate)
+				diags = append(diags, diag)
+			}
+			continue
+		}
+		if m != nil {
+			m.Diagnostics(callDiags)
+		}
+		mapDiagnostics(callDiags, mapTemplate)
+		diags = append(diags, callDiags...)
+
+		out.Write(src[last:str.start])
+		out.Write(jsonValue(result))
+		last = str.end
+		count++
+	}
+	if count == 0 {
+		return src, 0, diags
+	}
+	out.Write(src[last:])
+
+	var indented bytes.Buffer
+	if err = json.Indent(&indented, out.Bytes(), "", jsonIndent(src)); err != nil {
+		return nil, 0, hcl.Diagnostics{{Severity: hcl.DiagError, Summary: "Invalid JSON result", Detail: err.Error()}}
+	}
+	return indented.Bytes(), count, diags
+}
+
+// jsonIndent returns the indentation of the first indented line of the document, two spaces by default.
+func jsonIndent(src []byte) string {
+	for _, line := range bytes.Split(src, []byte("\n")) {
+		if trimmed := bytes.TrimLeft(line, " \t"); len(trimmed) > 0 && len(trimmed) < len(line) {
+			return string(line[:len(line)-len(trimmed)])
+		}
+	}
+	return "  "
+}
+
+// jsonValue returns the JSON value of the HCL expression source: an object if it is an object literal
+// with constant keys, an array if it is a tuple literal, their items being literal values or strings
+// interpolating their expressions, otherwise the value of jsonLiteral.
+func jsonValue(expr []byte) []byte {
+	parsed, diags := hclsyntax.ParseExpression(expr, "", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return jsonTemplate(expr)
+	}
+
+	var b bytes.Buffer
+	switch parsed := parsed.(type) {
+	case *hclsyntax.ObjectConsExpr:
+		b.WriteByte('{')
+		for i, item := range parsed.Items {
+			k := objectKey(expr, item.KeyExpr)
+			if strings.HasPrefix(k, "\x00") {
+				return jsonTemplate(expr)
+			}
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.Write(jsonQuote(k))
+			b.WriteByte(':')
+			b.Write(jsonLiteral(expr, item.ValueExpr))
+		}
+		b.WriteByte('}')
+	case *hclsyntax.TupleConsExpr:
+		b.WriteByte('[')
+		for i, elem := range parsed.Exprs {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.Write(jsonLiteral(expr, elem))
+		}
+		b.WriteByte(']')
+	default:
+		return jsonLiteral(expr, parsed)
+	}
+	return b.Bytes()
+}
+
+// jsonLiteral returns the JSON value of a constant string, number, bool or null,
+// otherwise a string interpolating the expression.
+func jsonLiteral(src []byte, expr hclsyntax.Expression) []byte {
+	v, diags := expr.Value(nil)
+	switch {
+	case diags.HasErrors() || !v.IsWhollyKnown():
+	case v.IsNull():
+		return []byte("null")
+	case v.Type() == cty.String:
+		// strings of the JSON syntax are templates
+		return jsonQuote(strings.NewReplacer("${", "$${", "%{", "%%{").Replace(v.AsString()))
+	case v.Type() == cty.Number:
+		return []byte(v.AsBigFloat().Text('g', -1))
+	case v.Type() == cty.Bool:
+		if v.True() {
+			return []byte("true")
+		}
+		return []byte("false")
+	}
+	if tmpl, ok := expr.(*hclsyntax.TemplateExpr); ok {
+		if s, ok := templateString(src, tmpl); ok {
+			return jsonQuote(s)
+		}
+	}
+	return jsonTemplate(rangeBytes(src, expr.Range()))
+}
+
+// templateString returns the JSON syntax string of a quoted template or heredoc such as "${var.name}-web",
+// which is a template itself, or false if it has directives.
+func templateString(src []byte, tmpl *hclsyntax.TemplateExpr) (string, bool) {
+	var b strings.Builder
+	for _, part := range tmpl.Parts {
+		if literal, ok := part.(*hclsyntax.LiteralValueExpr); ok && literal.Val.Type() == cty.String {
+			b.WriteString(strings.NewReplacer("${", "$${", "%{", "%%{").Replace(literal.Val.AsString()))
+			continue
+		}
+		r := part.Range()
+		if r.Start.Byte < 2 || string(src[r.Start.Byte-2:r.Start.Byte]) != "${" {
+			return "", false
+		}
+		b.WriteString("${" + string(rangeBytes(src, r)) + "}")
+	}
+	return b.String(), true
+}
+
+// jsonTemplate returns a JSON string interpolating the HCL expression.
+func jsonTemplate(expr []byte) []byte {
+	return jsonQuote("${" + string(expr) + "}")
+}
+
+// jsonQuote returns the JSON string of s without escaping HTML characters.
+func jsonQuote(s string) []byte {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n"))
+}
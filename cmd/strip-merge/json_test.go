@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	hcljson "github.com/hashicorp/hcl/v2/json"
+)
+
+func TestIsJSON(t *testing.T) {
+	tests := []struct {
+		name, src string
+		want      bool
+	}{
+		{"main.tf.json", "", true},
+		{"terragrunt.hcl.json", "", true},
+		{stdinName, "\n  {\"a\": 1}", true},
+		{stdinName, "a = {}", false},
+		{"main.tf", "# {", false},
+	}
+	for _, tt := range tests {
+		if got := isJSON(tt.name, []byte(tt.src)); got != tt.want {
+			t.Errorf("isJSON(%q, %q) = %v, want %v", tt.name, tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestJSONStrings(t *testing.T) {
+	src := `{"a": "x", "b": {"c": ["y", 1, {"d": "z"}], "e": null}, "f" : "w\"q"}`
+	strs, err := jsonStrings([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, str := range strs {
+		if quoted := src[str.start:str.end]; quoted[0] != '"' || quoted[len(quoted)-1] != '"' {
+			t.Errorf("string %q is located at %s", str.value, quoted)
+		}
+		got = append(got, str.value)
+	}
+	if want := []string{"x", "y", "z", `w"q`}; !slices.Equal(got, want) {
+		t.Errorf("jsonStrings() = %q, want %q", got, want)
+	}
+}
+
+func TestRewriteJSON(t *testing.T) {
+	src := `{
+  "locals": {
+    "plain": "${var.x}",
+    "tags": "${merge(var.tags, {Name = \"web\", Count = 2, On = true, Env = var.env})}",
+    "list": ["${merge({a = 1}, {a = 2, b = \"<&>\"})}", 1]
+  },
+  "resource": {"aws_instance": {"web": {"tags": "${merge(var.t, {(var.k) = 1})}"}}},
+  "inputs": "${merge(var.a, {x = \"$${literal}\"}, var.b)}"
+}
+`
+	want := `{
+  "locals": {
+    "plain": "${var.x}",
+    "tags": {
+      "Name": "web",
+      "Count": 2,
+      "On": true,
+      "Env": "${var.env}"
+    },
+    "list": [
+      {
+        "a": 2,
+        "b": "<&>"
+      },
+      1
+    ]
+  },
+  "resource": {
+    "aws_instance": {
+      "web": {
+        "tags": "${{(var.k) = 1}}"
+      }
+    }
+  },
+  "inputs": {
+    "x": "$${literal}"
+  }
+}
+`
+	got, count, diags := rewriteJSON("main.tf.json", []byte(src), options{strategy: strategyLargest, fold: true, funcs: defaultOptions.funcs})
+	if diags.HasErrors() {
+		t.Fatal(diags.Error())
+	}
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if count != 4 {
+		t.Errorf("rewriteJSON() = %d, want 4", count)
+	}
+	if len(diags) != 1 || diags[0].Subject.Start.Line != 5 {
+		t.Errorf("diagnostics = %v, want the overridden key at line 5", diags)
+	}
+
+	if _, diags = hcljson.Parse(got, "main.tf.json"); diags.HasErrors() {
+		t.Errorf("invalid result: %s", diags.Error())
+	}
+	if _, diags = hclsyntax.ParseTemplate([]byte("${{(var.k) = 1}}"), "", hcl.Pos{Line: 1, Column: 1}); diags.HasErrors() {
+		t.Errorf("invalid template: %s", diags.Error())
+	}
+
+	unchanged := []byte(`{"a": "${merge(var.a, var.b)}"}`)
+	if got, count, _ = rewriteJSON("main.tf.json", unchanged, defaultOptions); count != 0 || string(got) != string(unchanged) {
+		t.Errorf("rewriteJSON() = %s, %d; want the source unchanged", got, count)
+	}
+}
+
+func TestRewriteJSON_Literals(t *testing.T) {
+	src := `{"variable": {"tags": {"default": "${merge({a = 1})}", "description": "${merge({a = 1})}"}},` +
+		`"locals": {"//": "${merge({a = 1})}", "tags": "${merge(var.t, {Name = \"${var.n}-web\", Ids = [\"x-${var.id}\"]})}"}}`
+	want := `{"variable":{"tags":{"default":"${merge({a = 1})}","description":"${merge({a = 1})}"}},` +
+		`"locals":{"//":"${merge({a = 1})}","tags":{"Name":"${var.n}-web","Ids":"${[\"x-${var.id}\"]}"}}}`
+	got, count, diags := rewriteJSON("main.tf.json", []byte(src), defaultOptions)
+	if diags.HasErrors() {
+		t.Fatal(diags.Error())
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, got); err != nil || count != 1 || compact.String() != want {
+		t.Errorf("rewriteJSON() = %s, %d; want %s", compact.String(), count, want)
+	}
+
+	attr, b := parseSingleAttribute(t, `a = "%{ if var.x }x%{ endif }"`)
+	if got := jsonLiteral(b, attr.Expr); string(got) != `"${\"%{ if var.x }x%{ endif }\"}"` {
+		t.Errorf("jsonLiteral() = %s; want the directive interpolated", got)
+	}
+}
+
+func TestRewriteJSON_Escapes(t *testing.T) {
+	// the decoded template is shorter than the string in the document: é, \" and 😀 are escaped
+	src := `{"a": "${merge({x = \"\u00e9\\\"\", y = \"\ud83d\ude00\", k = 1}, {k = 2})}"}`
+	opts := defaultOptions
+	opts.fold = true
+	_, count, diags := rewriteJSON("main.tf.json", []byte(src), opts)
+	if count != 1 || len(diags) != 1 || diags[0].Subject == nil {
+		t.Fatalf("rewriteJSON() = %d, %v; want an overridden key", count, diags)
+	}
+	subject := diags[0].Subject
+	start := strings.Index(src, "k = 1")
+	if subject.Filename != "main.tf.json" || subject.Start.Line != 1 || subject.Start.Column != start+1 ||
+		subject.Start.Byte != start || subject.End.Byte != start+1 {
+		t.Errorf("subject = %+v; want the key at column %d", *subject, start+1)
+	}
+	if want := `Key "k" is overridden at line 1.`; diags[0].Detail != want {
+		t.Errorf("detail = %q, want %q", diags[0].Detail, want)
+	}
+}
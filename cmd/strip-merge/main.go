@@ -1,9 +1,13 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"strings"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
@@ -13,75 +17,162 @@ import (
 )
 
 const (
-	appName = "strip-merge"
-	nullHcl = "null.hcl"
+	appName   = "strip-merge"
+	nullHcl   = "null.hcl"
+	stdinName = "<stdin>"
 )
 
-func largestMergeLiteral(src []byte, attr *hclsyntax.Attribute) ([]byte, bool) {
-	call, ok := attr.Expr.(*hclsyntax.FunctionCallExpr)
-	if ok && call.Name == "merge" {
-		var bestObj *hclsyntax.ObjectConsExpr
-		var bestSize int
+var version, gitCommit string // -ldflags -X main.version=v0.0.0 -X main.gitCommit=[[:xdigit:]]
 
-		for _, arg := range call.Args {
-			if obj, ok := arg.(*hclsyntax.ObjectConsExpr); ok {
-				objRange := obj.SrcRange // original text of argument
-				objSize := objRange.End.Byte - objRange.Start.Byte
-
-				if bestObj == nil || objSize > bestSize {
-					bestObj, bestSize = obj, objSize
-				}
+// rewriteBody replaces merge(...) and other function call attributes of the body and of all its nested blocks
+// at any depth with their literal arguments selected according to the options. Blocks of both trees are matched by their position,
+// attributes by their names. The path consists of the types and labels of the blocks enclosing the body.
+// It returns the number of attributes rewritten and warnings about them.
+func rewriteBody(src []byte, syntaxBody *hclsyntax.Body, writeBody *hclwrite.Body, path []string, opts options) (int, hcl.Diagnostics) {
+	count, diags := 0, hcl.Diagnostics(nil)
+	for _, name := range ut.Arrange(ut.Keys(syntaxBody.Attributes)) {
+		attr := syntaxBody.Attributes[name]
+		if writeBody.GetAttribute(name) == nil || !opts.selector.Selected(append(slices.Clip(path), name)) {
+			continue
+		}
+		exprSrc, expr, m := src, hclsyntax.Expression(attr.Expr), remapper(nil)
+		if opts.defs != nil {
+			exprSrc, expr, m = inlineReferences(src, expr, opts.defs, opts.funcs)
+		}
+		if objBytes, callDiags, ok := reduceCall(exprSrc, expr, opts); ok {
+			if m != nil {
+				m.Diagnostics(callDiags)
+			}
+			// replace entire attribute with raw expression
+			writeBody.SetAttributeRaw(name, hclwrite.Tokens{
+				&hclwrite.Token{
+					Type:  hclsyntax.TokenIdent,
+					Bytes: objBytes,
+				},
+			})
+			diags = append(diags, callDiags...)
+			count++
+		} else if diag := unreducedCall(expr, opts); diag != nil {
+			if m != nil {
+				m.Diagnostics(hcl.Diagnostics{diag})
 			}
+			diags = append(diags, diag)
 		}
+	}
 
-		if bestObj != nil {
-			r := bestObj.SrcRange
-			return src[r.Start.Byte:r.End.Byte], true
+	writeBlocks := writeBody.Blocks()
+	for i, block := range syntaxBody.Blocks {
+		if i >= len(writeBlocks) || !sameBlock(block, writeBlocks[i]) {
+			break // trees do not match, which must never happen
 		}
+		blockPath := slices.Concat(path, []string{block.Type}, block.Labels)
+		n, blockDiags := rewriteBody(src, block.Body, writeBlocks[i].Body(), blockPath, opts)
+		count, diags = count+n, append(diags, blockDiags...)
 	}
-	return nil, false
+	return count, diags
 }
 
-func checkDiags(diags hcl.Diagnostics, w io.Writer, msg string, rc int) {
-	if diags.HasErrors() {
-		length := len(diags)
-		_, _ = fmt.Fprintf(w, "%s: %d diagnostic(s):\n\n", msg, length)
-		for i := 0; i < length; i++ {
-			_, _ = fmt.Fprintf(w, "%s: %s\n", diags[i].Summary, diags[i].Detail)
-		}
-		if rc > 0 {
-			os.Exit(rc)
-		}
-	}
+// sameBlock reports whether both blocks have the same type and labels.
+func sameBlock(syntaxBlock *hclsyntax.Block, writeBlock *hclwrite.Block) bool {
+	return syntaxBlock.Type == writeBlock.Type() && slices.Equal(syntaxBlock.Labels, writeBlock.Labels())
 }
 
 func main() {
-	src, err := io.ReadAll(os.Stdin)
-	ut.IsErr(err, 201, appName)
+	var helpFlag, versionFlag, writeFlag, diffFlag, checkFlag, resolveFlag bool
+	var nonLiteral, funcs, only, skip, diagFormat string
+	opts := defaultOptions
 
-	// syntax AST - read only
-	syntaxFile, diags := hclsyntax.ParseConfig(src, nullHcl, hcl.Pos{Line: 1, Column: 1})
-	checkDiags(diags, os.Stderr, "Failed to parse for reading", 202)
+	flag.BoolVar(&helpFlag, "help", false, "Display help message")
+	flag.BoolVar(&versionFlag, "version", false, "Show version information")
+	flag.StringVar(&opts.strategy, "strategy", opts.strategy, "Object literal argument to keep: "+strings.Join(strategies, ", "))
+	flag.BoolVar(&opts.fold, "fold", false, "Fold merge() of object literals only into a single object, reporting overridden keys")
+	flag.StringVar(&nonLiteral, "non-literal", nonLiteralDrop, "Non-literal arguments: drop them, or keep them in a reduced merge()")
+	flag.StringVar(&funcs, "funcs", strings.Join(opts.funcs, ","), "Comma-separated functions to reduce: "+strings.Join(ut.Arrange(ut.Keys(funcRules)), ", ")+
+		"; tomap() and try() around them are always looked through")
+	flag.StringVar(&only, "only", "", "Comma-separated paths of attributes to rewrite, e.g. locals.tags,resource.aws_instance.*.tags")
+	flag.StringVar(&skip, "skip", "", "Comma-separated paths of attributes not to rewrite")
+	flag.BoolVar(&resolveFlag, "resolve", false, "Inline literal locals and variable defaults of the module directory into calls")
+	flag.StringVar(&diagFormat, "diagnostics", diagText, "Format of diagnostics written to stderr: "+strings.Join(diagFormats, ", "))
+	flag.BoolVar(&writeFlag, "w", false, "Write results to the files instead of stdout")
+	flag.BoolVar(&diffFlag, "d", false, "Print diffs instead of results")
+	flag.BoolVar(&checkFlag, "check", false, "List files which would change and exit with 1 if there are any")
+	flag.Parse()
 
-	// write AST - write only
-	writeFile, diags := hclwrite.ParseConfig(src, nullHcl, hcl.Pos{Line: 1, Column: 1})
-	checkDiags(diags, os.Stderr, "Failed to parse for writing", 203)
+	if helpFlag {
+		fmt.Fprintln(os.Stderr, "Usage: "+appName+" [-help] [-version] [-strategy "+strings.Join(strategies, "|")+"] [-non-literal drop|keep] [-fold] [-funcs merge,...] [-only path,...] [-skip path,...] [-resolve] [-diagnostics text|json|sarif] [-w | -check] [-d] [file|directory ...]")
+		os.Exit(0)
+	}
 
-	writeBody := writeFile.Body()
-	syntaxBody := syntaxFile.Body.(*hclsyntax.Body)
+	if versionFlag {
+		fmt.Fprintf(os.Stderr, "Version: %s, Commit: %s\n", version, gitCommit)
+		os.Exit(0)
+	}
 
-	for name, attr := range syntaxBody.Attributes {
-		if objBytes, ok := largestMergeLiteral(src, attr); ok {
-			// replace entire attribute with raw expression
-			writeBody.SetAttributeRaw(name, hclwrite.Tokens{
-				&hclwrite.Token{
-					Type:  hclsyntax.TokenIdent,
-					Bytes: objBytes,
-				},
-			})
+	if !slices.Contains(strategies, opts.strategy) {
+		fmt.Fprintln(os.Stderr, "-strategy must be one of:", strategies)
+		os.Exit(2)
+	}
+
+	if !slices.Contains(nonLiteralModes, nonLiteral) {
+		fmt.Fprintln(os.Stderr, "-non-literal must be one of:", nonLiteralModes)
+		os.Exit(2)
+	}
+	opts.keepNonLiteral = nonLiteral == nonLiteralKeep
+
+	opts.funcs = strings.Split(funcs, ",")
+	for _, name := range opts.funcs {
+		if _, ok := funcRules[name]; !ok {
+			fmt.Fprintln(os.Stderr, "-funcs must be a list of:", ut.Arrange(ut.Keys(funcRules)))
+			os.Exit(2)
+		}
+	}
+
+	var err error
+	if opts.selector.only, err = parsePatterns(only); err != nil {
+		fmt.Fprintln(os.Stderr, "-only:", err)
+		os.Exit(2)
+	}
+	if opts.selector.skip, err = parsePatterns(skip); err != nil {
+		fmt.Fprintln(os.Stderr, "-skip:", err)
+		os.Exit(2)
+	}
+
+	if !slices.Contains(diagFormats, diagFormat) {
+		fmt.Fprintln(os.Stderr, "-diagnostics must be one of:", diagFormats)
+		os.Exit(2)
+	}
+
+	if writeFlag && checkFlag {
+		fmt.Fprintln(os.Stderr, "-w and -check are mutually exclusive")
+		os.Exit(2)
+	}
+
+	r := &runner{opts: opts, resolve: resolveFlag, write: writeFlag, diff: diffFlag, check: checkFlag,
+		stdout: os.Stdout, stderr: os.Stderr, format: diagFormat}
+
+	if flag.NArg() == 0 {
+		if writeFlag {
+			fmt.Fprintln(os.Stderr, "-w requires file or directory arguments")
+			os.Exit(2)
+		}
+
+		src, err := io.ReadAll(os.Stdin)
+		ut.IsErr(err, 201, appName)
+
+		err = r.handle(stdinName, src, 0, true)
+		ut.IsErr(r.Flush(), 204, appName)
+		if errors.Is(err, errParse) {
+			os.Exit(202)
+		}
+	} else {
+		r.run(flag.Args())
+		ut.IsErr(r.Flush(), 204, appName)
+		if r.failed > 0 {
+			os.Exit(203)
 		}
 	}
 
-	formatted := hclwrite.Format(writeFile.Bytes())
-	_, _ = os.Stdout.Write(formatted)
+	if checkFlag && r.changed > 0 {
+		os.Exit(1)
+	}
 }
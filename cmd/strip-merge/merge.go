@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Strategies of selecting object literal arguments of merge()
+const (
+	strategyLargest  = "largest"   // the literal with the most source bytes
+	strategyFirst    = "first"     // the first literal
+	strategyLast     = "last"      // the last literal
+	strategyMostKeys = "most-keys" // the literal with the most items
+	strategyUnion    = "union"     // all literals combined, later keys winning as merge() does
+)
+
+var strategies = []string{strategyLargest, strategyFirst, strategyLast, strategyMostKeys, strategyUnion}
+
+// Handling of arguments of merge() other than object literals
+const (
+	nonLiteralDrop = "drop" // the result is the selected object literal alone
+	nonLiteralKeep = "keep" // the result is a reduced merge() of non-literal arguments and the selected literal
+)
+
+var nonLiteralModes = []string{nonLiteralDrop, nonLiteralKeep}
+
+// options - how merge() and other function calls are reduced
+type options struct {
+	strategy       string
+	keepNonLiteral bool
+	fold           bool        // fold merge() of object literals only into a single object first
+	funcs          []string    // names of funcRules to apply
+	selector       selector    // attributes to rewrite
+	defs           definitions // literal locals and variable defaults to inline into calls, or nil
+}
+
+var defaultOptions = options{strategy: strategyLargest, funcs: []string{"merge"}}
+
+// collection - a kind of literals combined by a function, e.g. objects by merge() or tuples by concat()
+type collection struct {
+	literal func(hclsyntax.Expression) bool
+	items   func(hclsyntax.Expression) int
+	union   func(src []byte, literals []hclsyntax.Expression) []byte
+}
+
+var (
+	objectLiterals = collection{
+		literal: func(expr hclsyntax.Expression) bool { _, ok := expr.(*hclsyntax.ObjectConsExpr); return ok },
+		items:   func(expr hclsyntax.Expression) int { return len(expr.(*hclsyntax.ObjectConsExpr).Items) },
+		union: func(src []byte, literals []hclsyntax.Expression) []byte {
+			objects := make([]*hclsyntax.ObjectConsExpr, len(literals))
+			for i, literal := range literals {
+				objects[i] = literal.(*hclsyntax.ObjectConsExpr)
+			}
+			return unionObject(src, objects)
+		},
+	}
+	tupleLiterals = collection{
+		literal: func(expr hclsyntax.Expression) bool { _, ok := expr.(*hclsyntax.TupleConsExpr); return ok },
+		items:   func(expr hclsyntax.Expression) int { return len(expr.(*hclsyntax.TupleConsExpr).Exprs) },
+		union:   concatTuple,
+	}
+)
+
+// rangeBytes returns the source text of the range.
+func rangeBytes(src []byte, r hcl.Range) []byte {
+	return src[r.Start.Byte:r.End.Byte]
+}
+
+// reduceLiterals returns the source text replacing the call of a function combining literals of the collection
+// according to the options: the selected literal argument, or a reduced call if non-literal arguments are kept.
+// It returns false if the call has no literal arguments, or there is nothing to reduce.
+func reduceLiterals(src []byte, call *hclsyntax.FunctionCallExpr, kind collection, opts options) ([]byte, bool) {
+	var literals []int // indexes of literal arguments
+	for i, arg := range call.Args {
+		if kind.literal(arg) {
+			literals = append(literals, i)
+		}
+	}
+	if len(literals) == 0 {
+		return nil, false
+	}
+	if opts.strategy == strategyUnion && opts.keepNonLiteral && len(literals) < len(call.Args) {
+		return unionRuns(src, call, kind)
+	}
+
+	selected := literals[0]
+	var selectedBytes []byte
+	switch opts.strategy {
+	case strategyLast:
+		selected = literals[len(literals)-1]
+	case strategyMostKeys:
+		for _, i := range literals[1:] {
+			if kind.items(call.Args[i]) > kind.items(call.Args[selected]) {
+				selected = i
+			}
+		}
+	case strategyUnion:
+		args := make([]hclsyntax.Expression, len(literals))
+		for j, i := range literals {
+			args[j] = call.Args[i]
+		}
+		selected, selectedBytes = literals[len(literals)-1], kind.union(src, args)
+	case strategyFirst:
+	default: // strategyLargest
+		size := func(i int) int { r := call.Args[i].Range(); return r.End.Byte - r.Start.Byte }
+		for _, i := range literals[1:] {
+			if size(i) > size(selected) {
+				selected = i
+			}
+		}
+	}
+	if selectedBytes == nil {
+		selectedBytes = rangeBytes(src, call.Args[selected].Range())
+	}
+
+	if !opts.keepNonLiteral || len(literals) == len(call.Args) {
+		return selectedBytes, true
+	}
+	if len(literals) == 1 {
+		return nil, false // nothing to drop
+	}
+
+	var args [][]byte
+	for i, arg := range call.Args {
+		switch {
+		case i == selected:
+			args = append(args, selectedBytes)
+		case !slices.Contains(literals, i):
+			args = append(args, rangeBytes(src, arg.Range()))
+		}
+	}
+	return callBytes(call, args), true
+}
+
+// unionRuns returns the source text of the call with every run of adjacent literal arguments combined
+// in its place, so that no literal moves across a non-literal argument overriding or preceding it.
+// It returns false if no literals are adjacent.
+func unionRuns(src []byte, call *hclsyntax.FunctionCallExpr, kind collection) ([]byte, bool) {
+	var args [][]byte
+	var run []hclsyntax.Expression
+	reduced := false
+	flush := func() {
+		switch {
+		case len(run) == 1:
+			args = append(args, rangeBytes(src, run[0].Range()))
+		case len(run) > 1:
+			args, reduced = append(args, kind.union(src, run)), true
+		}
+		run = nil
+	}
+	for _, arg := range call.Args {
+		if kind.literal(arg) {
+			run = append(run, arg)
+			continue
+		}
+		flush()
+		args = append(args, rangeBytes(src, arg.Range()))
+	}
+	flush()
+	if !reduced {
+		return nil, false
+	}
+	return callBytes(call, args), true
+}
+
+// callBytes returns the source text of a call of the function with the arguments,
+// on a single line or one argument per line like the call itself.
+func callBytes(call *hclsyntax.FunctionCallExpr, args [][]byte) []byte {
+	if r := call.Range(); r.Start.Line == r.End.Line {
+		return slices.Concat([]byte(call.Name+"("), bytes.Join(args, []byte(", ")), []byte(")"))
+	}
+	return slices.Concat([]byte(call.Name+"(\n"), bytes.Join(args, []byte(",\n")), []byte(",\n)"))
+}
+
+// objectKey returns the key of an object item: the string value of a constant key,
+// otherwise its source text (which never equals a constant key).
+func objectKey(src []byte, expr hclsyntax.Expression) string {
+	if v, diags := expr.Value(nil); !diags.HasErrors() && v.IsKnown() && !v.IsNull() && v.Type() == cty.String {
+		return v.AsString()
+	}
+	return "\x00" + string(rangeBytes(src, expr.Range()))
+}
+
+// unionObject returns the source text of an object literal having the items of all objects;
+// the value of a key given several times is the last one, its position is the first one.
+func unionObject(src []byte, objects []*hclsyntax.ObjectConsExpr) []byte {
+	var keys []string
+	items := map[string]hclsyntax.ObjectConsItem{}
+	for _, obj := range objects {
+		for _, item := range obj.Items {
+			k := objectKey(src, item.KeyExpr)
+			if _, ok := items[k]; !ok {
+				keys = append(keys, k)
+			}
+			items[k] = item
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString("{\n")
+	for _, k := range keys {
+		b.Write(rangeBytes(src, items[k].KeyExpr.Range()))
+		b.WriteString(" = ")
+		b.Write(rangeBytes(src, items[k].ValueExpr.Range()))
+		b.WriteString("\n")
+	}
+	b.WriteString("}")
+	return b.Bytes()
+}
+
+// concatTuple returns the source text of a tuple literal having the elements of all tuples in their order.
+func concatTuple(src []byte, tuples []hclsyntax.Expression) []byte {
+	var elems [][]byte
+	for _, tuple := range tuples {
+		for _, expr := range tuple.(*hclsyntax.TupleConsExpr).Exprs {
+			elems = append(elems, rangeBytes(src, expr.Range()))
+		}
+	}
+	return slices.Concat([]byte("["), bytes.Join(elems, []byte(", ")), []byte("]"))
+}
@@ -2,10 +2,12 @@ package main
 
 import (
 	"bytes"
+	"strconv"
 	"testing"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
 )
 
 func parseSingleAttribute(t *testing.T, src string) (*hclsyntax.Attribute, []byte) {
@@ -30,14 +32,14 @@ func parseSingleAttribute(t *testing.T, src string) (*hclsyntax.Attribute, []byt
 	panic("unreachable")
 }
 
-func TestLargestMergeLiteral_Single(t *testing.T) {
+func TestReduceCall_MergeSingle(t *testing.T) {
 	attr, src := parseSingleAttribute(t, `
 inputs = merge(a, {
   x = 1
 })
 `)
 
-	obj, ok := largestMergeLiteral(src, attr)
+	obj, _, ok := reduceCall(src, attr.Expr, options{strategy: strategyLargest, funcs: []string{"merge"}})
 	if !ok {
 		t.Fatalf("expected object")
 	}
@@ -47,7 +49,7 @@ inputs = merge(a, {
 	}
 }
 
-func TestLargestMergeLiteral_Multiple(t *testing.T) {
+func TestReduceCall_MergeMultiple(t *testing.T) {
 	attr, src := parseSingleAttribute(t, `
 inputs = merge(
   { a = 1 },
@@ -56,7 +58,7 @@ inputs = merge(
 )
 `)
 
-	obj, ok := largestMergeLiteral(src, attr)
+	obj, _, ok := reduceCall(src, attr.Expr, options{strategy: strategyLargest, funcs: []string{"merge"}})
 	if !ok {
 		t.Fatalf("expected object")
 	}
@@ -67,24 +69,192 @@ inputs = merge(
 	}
 }
 
-func TestLargestMergeLiteral_None(t *testing.T) {
+func TestReduceCall_MergeNone(t *testing.T) {
 	attr, src := parseSingleAttribute(t, `
 inputs = merge(a, b, c)
 `)
 
-	_, ok := largestMergeLiteral(src, attr)
+	_, _, ok := reduceCall(src, attr.Expr, options{strategy: strategyLargest, funcs: []string{"merge"}})
 	if ok {
 		t.Fatalf("expected no object")
 	}
 }
 
-func TestLargestMergeLiteral_NotMerge(t *testing.T) {
+func TestReduceCall_MergeNotMerge(t *testing.T) {
 	attr, src := parseSingleAttribute(t, `
 inputs = { a = 1 }
 `)
 
-	_, ok := largestMergeLiteral(src, attr)
+	_, _, ok := reduceCall(src, attr.Expr, options{strategy: strategyLargest, funcs: []string{"merge"}})
 	if ok {
 		t.Fatalf("expected no object")
 	}
 }
+
+func rewriteSource(t *testing.T, src string, opts options) (string, int) {
+	t.Helper()
+
+	return rewriteSourceDiags(t, src, opts, nil)
+}
+
+func rewriteSourceDiags(t *testing.T, src string, opts options, diags *hcl.Diagnostics) (string, int) {
+	t.Helper()
+
+	b := []byte(src)
+	syntaxFile, parseDiags := hclsyntax.ParseConfig(b, nullHcl, hcl.Pos{Line: 1, Column: 1})
+	if parseDiags.HasErrors() {
+		t.Fatalf("parse error: %s", parseDiags.Error())
+	}
+	writeFile, parseDiags := hclwrite.ParseConfig(b, nullHcl, hcl.Pos{Line: 1, Column: 1})
+	if parseDiags.HasErrors() {
+		t.Fatalf("parse error: %s", parseDiags.Error())
+	}
+
+	count, rewriteDiags := rewriteBody(b, syntaxFile.Body.(*hclsyntax.Body), writeFile.Body(), nil, opts)
+	if diags != nil {
+		*diags = rewriteDiags
+	}
+	return string(hclwrite.Format(writeFile.Bytes())), count
+}
+
+func TestRewriteBody_NestedBlocks(t *testing.T) {
+	got, count := rewriteSource(t, `inputs = merge(a, { x = 1 })
+
+locals {
+  tags = merge(local.common, { Name = "x" })
+  other = 1
+}
+
+module "vpc" {
+  source = "./vpc"
+  tags   = merge(var.tags, { Team = "net" })
+
+  dynamic "subnet" {
+    for_each = var.subnets
+    content {
+      tags = merge(var.tags, { Tier = "private" })
+    }
+  }
+}
+
+resource "aws_instance" "web" {
+  tags = merge(var.tags, { Role = "web" }, { Big = "yes", Bigger = "yes" })
+}
+
+resource "aws_instance" "db" {
+  tags = var.tags
+}
+`, defaultOptions)
+
+	want := `inputs = { x = 1 }
+
+locals {
+  tags  = { Name = "x" }
+  other = 1
+}
+
+module "vpc" {
+  source = "./vpc"
+  tags   = { Team = "net" }
+
+  dynamic "subnet" {
+    for_each = var.subnets
+    content {
+      tags = { Tier = "private" }
+    }
+  }
+}
+
+resource "aws_instance" "web" {
+  tags = { Big = "yes", Bigger = "yes" }
+}
+
+resource "aws_instance" "db" {
+  tags = var.tags
+}
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if count != 5 {
+		t.Errorf("rewriteBody() = %d, want 5", count)
+	}
+}
+
+func TestReduceMergeCall_Strategies(t *testing.T) {
+	const src = `inputs = merge(
+  local.a,
+  { a = 1, b = 2 },
+  var.b,
+  { c = "a much longer value" },
+  { a = 3, d = 4, e = 5 },
+)
+`
+	tests := []struct {
+		strategy string
+		keep     bool
+		want     string
+	}{
+		{strategyLargest, false, `{ c = "a much longer value" }`},
+		{strategyFirst, false, `{ a = 1, b = 2 }`},
+		{strategyLast, false, `{ a = 3, d = 4, e = 5 }`},
+		{strategyMostKeys, false, `{ a = 3, d = 4, e = 5 }`},
+		{strategyUnion, false, "{\na = 3\nb = 2\nc = \"a much longer value\"\nd = 4\ne = 5\n}"},
+		{strategyLargest, true, "merge(\nlocal.a,\nvar.b,\n{ c = \"a much longer value\" },\n)"},
+		{strategyFirst, true, "merge(\nlocal.a,\n{ a = 1, b = 2 },\nvar.b,\n)"},
+		{strategyUnion, true, "merge(\nlocal.a,\n{ a = 1, b = 2 },\nvar.b,\n{\nc = \"a much longer value\"\na = 3\nd = 4\ne = 5\n},\n)"},
+	}
+
+	attr, b := parseSingleAttribute(t, src)
+	for _, tt := range tests {
+		t.Run(tt.strategy+"/"+strconv.FormatBool(tt.keep), func(t *testing.T) {
+			got, _, ok := reduceCall(b, attr.Expr, options{strategy: tt.strategy, keepNonLiteral: tt.keep, funcs: []string{"merge"}})
+			if !ok || string(got) != tt.want {
+				t.Errorf("reduceCall() = %q, %v; want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestReduceMergeCall_Keep(t *testing.T) {
+	attr, b := parseSingleAttribute(t, `inputs = merge(a, { x = 1 }, b, { y = 2 })`)
+	if got, _, ok := reduceCall(b, attr.Expr, options{strategy: strategyLast, keepNonLiteral: true, funcs: []string{"merge"}}); !ok ||
+		string(got) != `merge(a, b, { y = 2 })` {
+		t.Errorf("reduceCall() = %q, %v; want a single-line call", got, ok)
+	}
+
+	for _, src := range []string{
+		`inputs = merge(a, { x = 1 }, b)`, // a single literal, nothing to drop
+		`inputs = merge(a, b)`,
+	} {
+		attr, b := parseSingleAttribute(t, src)
+		if got, _, ok := reduceCall(b, attr.Expr, options{strategy: strategyUnion, keepNonLiteral: true, funcs: []string{"merge"}}); ok {
+			t.Errorf("reduceCall(%s) = %q; want no reduction", src, got)
+		}
+	}
+
+	// literals separated by a non-literal argument are never combined across it
+	for _, src := range []string{
+		`inputs = merge({ a = 1 }, var.x, { b = 2 })`,
+		`inputs = concat(["a"], var.x, ["b"])`,
+	} {
+		attr, b := parseSingleAttribute(t, src)
+		call := attr.Expr.(*hclsyntax.FunctionCallExpr)
+		kind := map[string]collection{"merge": objectLiterals, "concat": tupleLiterals}[call.Name]
+		if got, ok := reduceLiterals(b, call, kind, options{strategy: strategyUnion, keepNonLiteral: true}); ok {
+			t.Errorf("reduceLiterals(%s) = %q; want no reduction", src, got)
+		}
+	}
+
+	attr, b = parseSingleAttribute(t, `inputs = concat(var.x, ["a"], ["b"], var.y, ["c"])`)
+	got, ok := reduceLiterals(b, attr.Expr.(*hclsyntax.FunctionCallExpr), tupleLiterals, options{strategy: strategyUnion, keepNonLiteral: true})
+	if want := `concat(var.x, ["a", "b"], var.y, ["c"])`; !ok || string(got) != want {
+		t.Errorf("reduceLiterals() = %q, %v; want %q", got, ok, want)
+	}
+
+	attr, b = parseSingleAttribute(t, `inputs = merge({ x = 1 }, { "x" = 2, (local.k) = 3 })`)
+	got, _, ok = reduceCall(b, attr.Expr, options{strategy: strategyUnion, keepNonLiteral: true, funcs: []string{"merge"}})
+	if want := "{\n\"x\" = 2\n(local.k) = 3\n}"; !ok || string(got) != want {
+		t.Errorf("reduceCall() = %q, %v; want %q", got, ok, want)
+	}
+}
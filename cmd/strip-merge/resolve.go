@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	hcljson "github.com/hashicorp/hcl/v2/json"
+)
+
+// definition - a literal local or variable default
+type definition struct {
+	src []byte    // the whole file
+	r   hcl.Range // the value
+}
+
+// definitions - literal locals and variable defaults keyed by their references,
+// e.g. "local.common_tags" or "var.tags"; nil if a reference is defined several times
+type definitions map[string]*definition
+
+// isLiteral reports whether the expression is a collection literal or a constant.
+func isLiteral(expr hclsyntax.Expression) bool {
+	switch expr.(type) {
+	case *hclsyntax.ObjectConsExpr, *hclsyntax.TupleConsExpr:
+		return true
+	}
+	_, ok := constantValue(expr)
+	return ok
+}
+
+// reference returns the reference of a local or a variable, e.g. "local.common_tags", or "".
+func reference(expr hclsyntax.Expression) string {
+	traversal, ok := expr.(*hclsyntax.ScopeTraversalExpr)
+	if !ok || len(traversal.Traversal) != 2 {
+		return ""
+	}
+	attr, ok := traversal.Traversal[1].(hcl.TraverseAttr)
+	if root := traversal.Traversal.RootName(); !ok || (root != "local" && root != "var") {
+		return ""
+	}
+	return traversal.Traversal.RootName() + "." + attr.Name
+}
+
+// add adds a definition; a reference defined again is never resolved.
+func (d definitions) add(ref string, def *definition) {
+	if _, ok := d[ref]; ok {
+		d[ref] = nil
+	} else {
+		d[ref] = def
+	}
+}
+
+// collect adds the literal locals and variable defaults of the configuration.
+// References to other locals or variables are kept as such to be followed by lookup.
+func (d definitions) collect(name string, src []byte) {
+	if isJSON(name, src) {
+		d.collectJSON(name, src)
+		return
+	}
+	file, diags := hclsyntax.ParseConfig(src, name, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return
+	}
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		switch {
+		case block.Type == "locals":
+			for name, attr := range block.Body.Attributes {
+				if isLiteral(attr.Expr) || reference(attr.Expr) != "" {
+					d.add("local."+name, &definition{src: src, r: attr.Expr.Range()})
+				}
+			}
+		case block.Type == "variable" && len(block.Labels) == 1:
+			if attr, ok := block.Body.Attributes["default"]; ok && isLiteral(attr.Expr) {
+				d.add("var."+block.Labels[0], &definition{src: src, r: attr.Expr.Range()})
+			}
+		}
+	}
+}
+
+// collectJSON adds the literal locals and variable defaults of the configuration in the HCL JSON syntax.
+// JSON values are valid in the native syntax as well, so they are inlined as they are; strings such as
+// "${local.common_tags}" are templates rather than references and are never followed.
+func (d definitions) collectJSON(name string, src []byte) {
+	file, diags := hcljson.Parse(src, name)
+	if diags.HasErrors() {
+		return
+	}
+	content, _, _ := file.Body.PartialContent(&hcl.BodySchema{Blocks: []hcl.BlockHeaderSchema{
+		{Type: "locals"},
+		{Type: "variable", LabelNames: []string{"name"}},
+	}})
+	literal := func(r hcl.Range) bool {
+		expr, diags := hclsyntax.ParseExpression(rangeBytes(src, r), name, r.Start)
+		return !diags.HasErrors() && isLiteral(expr)
+	}
+	for _, block := range content.Blocks {
+		attrs, _ := block.Body.JustAttributes()
+		switch block.Type {
+		case "locals":
+			for name, attr := range attrs {
+				if literal(attr.Expr.Range()) {
+					d.add("local."+name, &definition{src: src, r: attr.Expr.Range()})
+				}
+			}
+		case "variable":
+			if attr, ok := attrs["default"]; ok && literal(attr.Expr.Range()) {
+				d.add("var."+block.Labels[0], &definition{src: src, r: attr.Expr.Range()})
+			}
+		}
+	}
+}
+
+// lookup returns the literal definition of the reference following references to other locals
+// or variables, or nil.
+func (d definitions) lookup(ref string) *definition {
+	for range len(d) + 1 { // a cycle ends here
+		def := d[ref]
+		if def == nil {
+			return nil
+		}
+		expr, diags := hclsyntax.ParseExpression(rangeBytes(def.src, def.r), "", hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			return nil
+		}
+		if ref = reference(expr); ref == "" {
+			return def
+		}
+	}
+	return nil
+}
+
+// dirDefinitions returns the definitions of every file of the directory having one of sourceExtensions, keyed by its path.
+func dirDefinitions(dir string) map[string]definitions {
+	files := map[string]definitions{}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return files
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if !entry.Type().IsRegular() || !slices.ContainsFunc(sourceExtensions, func(ext string) bool {
+			return strings.HasSuffix(path, ext)
+		}) {
+			continue
+		}
+		if b, err := os.ReadFile(path); err == nil {
+			defs := definitions{}
+			defs.collect(path, b)
+			files[path] = defs
+		}
+	}
+	return files
+}
+
+// moduleDefinitions returns the definitions of the files of the module directory, see dirDefinitions,
+// the source of the file itself replacing its content on disk.
+func moduleDefinitions(path string, src []byte, files map[string]definitions) definitions {
+	defs := definitions{}
+	defs.collect(path, src)
+	for sibling, siblingDefs := range files {
+		if sibling == filepath.Clean(path) {
+			continue
+		}
+		for ref, def := range siblingDefs { // nil stays nil
+			defs.add(ref, def)
+		}
+	}
+	return defs
+}
+
+// piece - a part of an inlined expression copied from a source
+type piece struct {
+	start int     // byte offset in the inlined expression
+	src   []byte  // the source copied from
+	pos   hcl.Pos // a position in the source at or before the copied bytes
+	from  int     // byte offset of the copied bytes in the source
+	file  string  // the name of the source
+}
+
+// posAfter returns the position of the byte offset of the source following the position.
+func posAfter(pos hcl.Pos, src []byte, offset int) hcl.Pos {
+	chunk := src[pos.Byte:offset]
+	if nl := bytes.LastIndexByte(chunk, '\n'); nl >= 0 {
+		return hcl.Pos{Line: pos.Line + bytes.Count(chunk, []byte("\n")), Column: len(chunk) - nl, Byte: offset}
+	}
+	return hcl.Pos{Line: pos.Line, Column: pos.Column + len(chunk), Byte: offset}
+}
+
+// remapper maps ranges of an inlined expression back to the sources of its pieces.
+type remapper []piece
+
+func (m remapper) pos(offset int) (hcl.Pos, string) {
+	i := len(m) - 1
+	for i > 0 && m[i].start > offset {
+		i--
+	}
+	p := m[i]
+	return posAfter(p.pos, p.src, p.from+offset-p.start), p.file
+}
+
+// Range maps the range of the inlined expression; a range spanning several pieces ends where its start piece ends.
+func (m remapper) Range(r hcl.Range) hcl.Range {
+	start, file := m.pos(r.Start.Byte)
+	end, endFile := m.pos(r.End.Byte)
+	if endFile != file || end.Byte < start.Byte {
+		end = start
+	}
+	return hcl.Range{Filename: file, Start: start, End: end}
+}
+
+// Diagnostics maps the subjects of diagnostics of the inlined expression.
+func (m remapper) Diagnostics(diags hcl.Diagnostics) {
+	mapDiagnostics(diags, m.Range)
+}
+
+// mapDiagnostics maps the subjects of diagnostics and the ranges they refer to.
+func mapDiagnostics(diags hcl.Diagnostics, mapRange func(hcl.Range) hcl.Range) {
+	for _, diag := range diags {
+		if diag.Subject != nil {
+			subject := mapRange(*diag.Subject)
+			diag.Subject = &subject
+		}
+		if o, ok := diag.Extra.(override); ok {
+			o.by = mapRange(o.by)
+			diag.Detail, diag.Extra = o.detail(*diag.Subject), o
+		}
+	}
+}
+
+// inlineReferences returns the source text of the expression with the arguments of a call of one of the functions,
+// possibly inside wrappers, which are references to literal locals or variable defaults replaced with their values,
+// the expression parsed from it, and the mapping of its ranges back to the sources. References nested in other
+// expressions such as jsondecode(local.raw) are kept. It returns the source and the expression themselves
+// and no mapping if nothing is replaced.
+func inlineReferences(src []byte, expr hclsyntax.Expression, defs definitions, funcs []string) ([]byte, hclsyntax.Expression, remapper) {
+	type replacement struct {
+		r   hcl.Range
+		def *definition
+	}
+	var replacements []replacement
+	var walk func(hclsyntax.Expression)
+	walk = func(expr hclsyntax.Expression) {
+		call, ok := expr.(*hclsyntax.FunctionCallExpr)
+		if !ok {
+			return
+		}
+		rule, ok := selectedRule(call.Name, funcs)
+		if !ok {
+			return
+		}
+		if rule.wrapper {
+			if len(call.Args) > 0 {
+				walk(call.Args[0])
+			}
+			return
+		}
+		for _, arg := range call.Args {
+			if def := defs.lookup(reference(arg)); def != nil {
+				replacements = append(replacements, replacement{arg.Range(), def})
+			}
+		}
+	}
+	walk(expr)
+	if len(replacements) == 0 {
+		return src, expr, nil
+	}
+
+	r := expr.Range()
+	var text []byte
+	var m remapper
+	last := r.Start.Byte
+	for _, rep := range replacements { // in the order of the source
+		m = append(m, piece{start: len(text), src: src, pos: r.Start, from: last, file: r.Filename})
+		text = append(text, src[last:rep.r.Start.Byte]...)
+		m = append(m, piece{start: len(text), src: rep.def.src, pos: rep.def.r.Start, from: rep.def.r.Start.Byte, file: rep.def.r.Filename})
+		text = append(text, rangeBytes(rep.def.src, rep.def.r)...)
+		last = rep.r.End.Byte
+	}
+	m = append(m, piece{start: len(text), src: src, pos: r.Start, from: last, file: r.Filename})
+	text = append(text, src[last:r.End.Byte]...)
+
+	parsed, diags := hclsyntax.ParseExpression(text, r.Filename, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return src, expr, nil
+	}
+	return text, parsed, m
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInlineReferences(t *testing.T) {
+	defs := definitions{}
+	defs.collect("locals.tf", []byte(`locals {
+  tags  = { Team = "core" }
+  alias = local.tags
+  list  = ["a"]
+  dyn   = merge(var.x, { b = 2 })
+  dup   = { a = 1 }
+  loop1 = local.loop2
+  loop2 = local.loop1
+  raw   = "{\"x\": 1}"
+}
+locals {
+  dup = { a = 2 }
+}
+variable "extra" {
+  default = { Extra = "yes" }
+}
+variable "required" {}
+`))
+
+	tests := []struct {
+		src, want string
+	}{
+		{`merge(local.tags, { Name = "x" })`, `merge({ Team = "core" }, { Name = "x" })`},
+		{`merge(local.alias, var.extra)`, `merge({ Team = "core" }, { Extra = "yes" })`},
+		{`try(concat(local.list, var.l), [])`, `try(concat(["a"], var.l), [])`},
+		{`merge(local.dyn, local.dup, local.loop1, var.required, local.missing)`, ``},
+		{`local.tags`, ``},
+		{`merge(jsondecode(local.raw), local.tags)`, `merge(jsondecode(local.raw), { Team = "core" })`},
+		{`merge(merge(local.tags, var.x), var.x)`, ``},
+		{`lookup(local.tags, "Team")`, ``},
+	}
+	funcs := []string{"merge", "concat", "try"}
+	for _, tt := range tests {
+		attr, src := parseSingleAttribute(t, "a = "+tt.src)
+		got, expr, m := inlineReferences(src, attr.Expr, defs, funcs)
+		if tt.want == "" {
+			if m != nil || expr != attr.Expr {
+				t.Errorf("inlineReferences(%s) = %s; want nothing replaced", tt.src, got)
+			}
+			continue
+		}
+		if string(got) != tt.want || m == nil {
+			t.Errorf("inlineReferences(%s) = %s; want %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestRunner_Resolve(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"locals.tf": `locals {
+  common_tags = {
+    Team = "core"
+    Env  = var.env
+  }
+}
+`,
+		"main.tf": `resource "aws_instance" "web" {
+  tags = merge(local.common_tags, { Name = "x", Team = "web" })
+}
+`,
+	})
+
+	var stdout, stderr bytes.Buffer
+	r := &runner{opts: defaultOptions, resolve: true, stdout: &stdout, stderr: &stderr}
+	r.opts.fold = true
+	r.run([]string{filepath.Join(dir, "main.tf")})
+
+	want := `resource "aws_instance" "web" {
+  tags = {
+    Env  = var.env
+    Name = "x"
+    Team = "web"
+  }
+}
+`
+	if stdout.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", stdout.String(), want)
+	}
+	warning := filepath.Join(dir, "locals.tf") + `:3,5: Overridden key: Key "Team" is overridden at ` + filepath.Join(dir, "main.tf") + ":2."
+	if got := strings.TrimSpace(stderr.String()); got != warning {
+		t.Errorf("warnings: %s; want %s", got, warning)
+	}
+}
+
+func TestCollectJSON(t *testing.T) {
+	defs := definitions{}
+	defs.collect("locals.tf.json", []byte(`{
+  "locals": {
+    "tags": {"Team": "core"},
+    "dyn": "${merge(var.x, {})}",
+    "alias": "${local.tags}"
+  },
+  "variable": {
+    "extra": {"default": ["a"]},
+    "required": {}
+  }
+}`))
+
+	tests := []struct {
+		ref, want string
+	}{
+		{"local.tags", `{"Team": "core"}`},
+		{"var.extra", `["a"]`},
+		{"local.dyn", ""},
+		{"local.alias", ""},
+		{"var.required", ""},
+	}
+	for _, tt := range tests {
+		def := defs.lookup(tt.ref)
+		if tt.want == "" {
+			if def != nil {
+				t.Errorf("lookup(%s) = %s; want nil", tt.ref, rangeBytes(def.src, def.r))
+			}
+			continue
+		}
+		if def == nil || string(rangeBytes(def.src, def.r)) != tt.want {
+			t.Errorf("lookup(%s) = %v; want %s", tt.ref, def, tt.want)
+		}
+	}
+}
+
+func TestRunner_ResolveDir(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"locals.tf.json": `{"locals": {"common_tags": {"Team": "core"}}}` + "\n",
+		"a.tf":           "a = merge(local.common_tags, { Name = \"a\" })\n",
+		"b.tf":           "b = merge(local.common_tags, { Name = \"b\" })\n",
+	})
+
+	var stdout, stderr bytes.Buffer
+	r := &runner{opts: defaultOptions, resolve: true, write: true, stdout: &stdout, stderr: &stderr}
+	r.opts.fold = true
+	r.run([]string{dir})
+	if r.failed != 0 || stderr.Len() != 0 {
+		t.Fatalf("failed %d: %s", r.failed, stderr.String())
+	}
+
+	for name, want := range map[string]string{
+		"a.tf": "a = {\n  \"Team\" : \"core\"\n  Name = \"a\"\n}\n", // JSON values are inlined as they are
+		"b.tf": "b = {\n  \"Team\" : \"core\"\n  Name = \"b\"\n}\n",
+	} {
+		if got, err := os.ReadFile(filepath.Join(dir, name)); err != nil || string(got) != want {
+			t.Errorf("%s:\n%s\nwant:\n%s", name, got, want)
+		}
+	}
+	if len(r.modules) != 1 || len(r.modules[dir]) != 3 {
+		t.Errorf("modules = %v; want the definitions of 3 files of %s", r.modules, dir)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// pathPattern - pattern of attribute paths such as "resource.aws_instance.*.tags": a path consists of
+// the types and labels of the enclosing blocks and the attribute name, and every segment of the pattern
+// matches a single segment of the path as path.Match does.
+type pathPattern []string
+
+// parsePatterns parses comma-separated path patterns.
+func parsePatterns(s string) ([]pathPattern, error) {
+	var patterns []pathPattern
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		pattern := pathPattern(strings.Split(p, "."))
+		for _, segment := range pattern {
+			if _, err := path.Match(segment, ""); err != nil || segment == "" {
+				return nil, fmt.Errorf("invalid path pattern: %s", p)
+			}
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// Match reports whether the attribute path matches the pattern.
+func (p pathPattern) Match(attrPath []string) bool {
+	if len(p) != len(attrPath) {
+		return false
+	}
+	for i, segment := range p {
+		if ok, _ := path.Match(segment, attrPath[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// String -
+func (p pathPattern) String() string {
+	return strings.Join(p, ".")
+}
+
+// selector - attributes to be rewritten: those matching any of only, if given, and none of skip
+type selector struct {
+	only, skip []pathPattern
+}
+
+// Selected reports whether the attribute of the path is to be rewritten.
+func (s *selector) Selected(attrPath []string) bool {
+	matchAny := func(patterns []pathPattern) bool {
+		for _, p := range patterns {
+			if p.Match(attrPath) {
+				return true
+			}
+		}
+		return false
+	}
+	return (len(s.only) == 0 || matchAny(s.only)) && !matchAny(s.skip)
+}
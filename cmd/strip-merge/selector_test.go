@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParsePatterns(t *testing.T) {
+	patterns, err := parsePatterns(" locals.tags, ,resource.aws_*.*.tags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patterns) != 2 || patterns[0].String() != "locals.tags" || patterns[1].String() != "resource.aws_*.*.tags" {
+		t.Errorf("parsePatterns() = %v", patterns)
+	}
+
+	for _, s := range []string{"a..b", ".a", "a.[", "a."} {
+		if _, err := parsePatterns(s); err == nil {
+			t.Errorf("parsePatterns(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestSelector(t *testing.T) {
+	only, _ := parsePatterns("locals.tags,module.vpc.tags,resource.aws_instance.*.tags")
+	skip, _ := parsePatterns("resource.*.db.tags")
+	s := selector{only: only, skip: skip}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"locals.tags", true},
+		{"locals.other", false},
+		{"tags", false},
+		{"module.vpc.tags", true},
+		{"module.eks.tags", false},
+		{"resource.aws_instance.web.tags", true},
+		{"resource.aws_instance.db.tags", false},
+		{"resource.aws_instance.web.root_block_device.tags", false},
+	}
+	for _, tt := range tests {
+		if got := s.Selected(strings.Split(tt.path, ".")); got != tt.want {
+			t.Errorf("Selected(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	if !(&selector{}).Selected([]string{"inputs"}) {
+		t.Errorf("an empty selector must select everything")
+	}
+}
+
+func TestRewriteBody_Selector(t *testing.T) {
+	only, _ := parsePatterns("locals.tags,resource.aws_instance.*.dynamic.subnet.content.tags")
+	opts := defaultOptions
+	opts.selector = selector{only: only}
+
+	got, count := rewriteSource(t, `inputs = merge(a, { x = 1 })
+locals {
+  tags  = merge(a, { x = 1 })
+  other = merge(a, { x = 1 })
+}
+resource "aws_instance" "web" {
+  tags = merge(a, { x = 1 })
+  dynamic "subnet" {
+    content {
+      tags = merge(a, { x = 1 })
+    }
+  }
+}
+`, opts)
+
+	want := `inputs = merge(a, { x = 1 })
+locals {
+  tags  = { x = 1 }
+  other = merge(a, { x = 1 })
+}
+resource "aws_instance" "web" {
+  tags = merge(a, { x = 1 })
+  dynamic "subnet" {
+    content {
+      tags = { x = 1 }
+    }
+  }
+}
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if count != 2 {
+		t.Errorf("rewriteBody() = %d, want 2", count)
+	}
+}
+
+func TestRewriteJSON_Selector(t *testing.T) {
+	skip, _ := parsePatterns("resource.*.*.tags")
+	opts := defaultOptions
+	opts.selector = selector{skip: skip}
+
+	src := `{"locals": {"tags": "${merge(a, {x = 1})}"}, "resource": {"aws_instance": {"web": [{"tags": "${merge(a, {x = 1})}"}]}}}`
+	want := `{"locals":{"tags":{"x":1}},"resource":{"aws_instance":{"web":[{"tags":"${merge(a, {x = 1})}"}]}}}`
+	got, count, diags := rewriteJSON("main.tf.json", []byte(src), opts)
+	if diags.HasErrors() {
+		t.Fatal(diags.Error())
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, got); err != nil || count != 1 || compact.String() != want {
+		t.Errorf("rewriteJSON() = %s, %d; want %s", got, count, want)
+	}
+}
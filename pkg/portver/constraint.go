@@ -0,0 +1,95 @@
+package portver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Constraint operators; a version without an operator must be equal.
+var operators = []string{">=", "<=", "!=", "==", ">", "<", "="}
+
+// term - a single comparison such as ">=1.2_1,1"
+type term struct {
+	op      string
+	version Version
+}
+
+func (t term) check(v Version) bool {
+	rc := v.Compare(t.version)
+	switch t.op {
+	case ">=":
+		return rc >= 0
+	case "<=":
+		return rc <= 0
+	case "!=":
+		return rc != 0
+	case ">":
+		return rc > 0
+	case "<":
+		return rc < 0
+	}
+	return rc == 0
+}
+
+// Constraint - alternatives separated by "||", each a list of terms separated by white space, all of which must hold.
+// Versions are compared with Version.Compare, so PORTEPOCH always wins: "1.9,1" does not match "<2".
+// Example: ">=1.2_1,1 <2,1 || =3.0"
+type Constraint [][]term
+
+// ParseConstraint parses a constraint expression such as ">=1.2_1,1 <2".
+func ParseConstraint(s string) (Constraint, error) {
+	var c Constraint
+	for _, alternative := range strings.Split(s, "||") {
+		fields := strings.Fields(alternative)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("%w: empty constraint in %q", ErrInvalid, s)
+		}
+		terms := make([]term, 0, len(fields))
+		for _, field := range fields {
+			t := term{op: "="}
+			for _, op := range operators {
+				if strings.HasPrefix(field, op) {
+					t.op, field = op, field[len(op):]
+					break
+				}
+			}
+			v, err := Parse(field)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q in %q", ErrInvalid, field, s)
+			}
+			t.version = v
+			terms = append(terms, t)
+		}
+		c = append(c, terms)
+	}
+	return c, nil
+}
+
+// Check reports whether the version satisfies the constraint.
+func (c Constraint) Check(v Version) bool {
+	for _, terms := range c {
+		ok := true
+		for _, t := range terms {
+			if ok = t.check(v); !ok {
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// String -
+func (c Constraint) String() string {
+	alternatives := make([]string, len(c))
+	for i, terms := range c {
+		s := make([]string, len(terms))
+		for j, t := range terms {
+			s[j] = t.op + t.version.String()
+		}
+		alternatives[i] = strings.Join(s, " ")
+	}
+	return strings.Join(alternatives, " || ")
+}
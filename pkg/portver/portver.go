@@ -0,0 +1,321 @@
+// Package portver parses, formats and compares FreeBSD port versions
+// (PORTVERSION, PORTREVISION and PORTEPOCH) the way pkg(8) does.
+package portver
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	ut "github.com/omilevskyi/go/pkg/utils"
+)
+
+// ErrInvalid - the string is not a valid version or constraint
+var ErrInvalid = errors.New("invalid version")
+
+// Version - FreeBSD port version, e.g. "1.2.3_4,5" is PORTVERSION 1.2.3, PORTREVISION 4 and PORTEPOCH 5
+type Version struct {
+	version  string // delimited by "."
+	revision int    // _${PORTREVISION} 20250707: 1..102
+	epoch    int    // ,${PORTEPOCH} 20250707: 1,2,3,4,6,8
+}
+
+// New -
+func New(portVersion string, portRevision, portEpoch int) Version {
+	return Version{version: portVersion, revision: portRevision, epoch: portEpoch}
+}
+
+// PortVersion -
+func (v Version) PortVersion() string {
+	return v.version
+}
+
+// PortRevision -
+func (v Version) PortRevision() int {
+	return v.revision
+}
+
+// PortEpoch -
+func (v Version) PortEpoch() int {
+	return v.epoch
+}
+
+// Components returns PORTVERSION split by ".", e.g. "1.2a.3" → []{"1", "2a", "3"}.
+func (v Version) Components() []string {
+	if v.version == "" {
+		return nil
+	}
+	return strings.Split(v.version, ".")
+}
+
+// IsZero reports whether the version is empty.
+func (v Version) IsZero() bool {
+	return v == Version{}
+}
+
+// String formats the version back to "1.2.3_4,5", omitting zero PORTREVISION and PORTEPOCH.
+func (v Version) String() string {
+	s := v.version
+	if v.revision > 0 {
+		s += "_" + strconv.Itoa(v.revision)
+	}
+	if v.epoch > 0 {
+		s += "," + strconv.Itoa(v.epoch)
+	}
+	return s
+}
+
+// Compare compares PORTEPOCH, then PORTVERSION components the way pkg(8) does, and finally PORTREVISION.
+// Returns -1 if v is older than w, 1 if newer, 0 if equal.
+func (v Version) Compare(w Version) int {
+	if v.epoch != w.epoch {
+		return cmp.Compare(v.epoch, w.epoch)
+	}
+	if rc := ComparePortVersion(v.version, w.version); rc != 0 {
+		return rc
+	}
+	return cmp.Compare(v.revision, w.revision)
+}
+
+// Compare is Version.Compare as a function, e.g. for slices.SortFunc.
+func Compare(a, b Version) int {
+	return a.Compare(b)
+}
+
+// intSuffix extracts an integer suffix from the input string `s`
+// following the last occurrence of the separator `sep`. It returns the
+// parsed integer (or 0 if parsing fails) and the trimmed string without the suffix.
+// Example: intSuffix("pkg-1.2.3_4,5", ',') → (5, "pkg-1.2.3_4")
+func intSuffix(s string, sep byte) (int, string) {
+	v := 0
+	if i := strings.LastIndexByte(s, sep); i >= 0 {
+		if i+1 < len(s) {
+			if num, err := strconv.Atoi(s[i+1:]); err == nil && num > 0 {
+				v = num
+			}
+		}
+		s = s[:i]
+	}
+	return v, s
+}
+
+// Parse parses a bare version string such as "1.2.3_4,5".
+// Missing, empty or non-numeric PORTREVISION and PORTEPOCH are zero.
+func Parse(s string) (Version, error) {
+	portEpoch, rest := intSuffix(s, ',')
+	portRevision, rest := intSuffix(rest, '_')
+	if rest == "" {
+		return Version{}, fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+	return Version{version: rest, revision: portRevision, epoch: portEpoch}, nil
+}
+
+// SplitName splits a "name-version" string into the package name and the version.
+// Example: SplitName("bash-5.2.37_1") → "bash", 5.2.37_1, true
+func SplitName(s string) (string, Version, bool) {
+	dash := strings.LastIndexByte(s, '-')
+	if dash <= 0 {
+		return "", Version{}, false
+	}
+	v, err := Parse(s[dash+1:])
+	if err != nil {
+		return "", Version{}, false
+	}
+	return s[:dash], v, true
+}
+
+// SplitFile splits the base name of a package file into the package name and the version,
+// removing the file extension if any (a suffix of digits is a version component, not an extension).
+// Example: SplitFile("/packages/All/some-pkg-name-1.2.3_4,5.pkg") → "some-pkg-name", 1.2.3_4,5, true
+func SplitFile(path string) (string, Version, bool) {
+	s := filepath.Base(path)
+	for i, isAllDigits := len(s)-1, true; 0 <= i && s[i] != ',' && s[i] != '_'; i-- {
+		if s[i] == '.' {
+			if !isAllDigits {
+				s = s[:i]
+			}
+			break
+		}
+		isAllDigits = isAllDigits && isDigit(s[i])
+	}
+	return SplitName(s)
+}
+
+// Special words recognised in place of a letter, and their letter values.
+var versionStages = []struct {
+	name  string
+	value int
+}{
+	{"pl", 0},
+	{"alpha", 'a' - 'a' + 1},
+	{"beta", 'b' - 'a' + 1},
+	{"pre", 'p' - 'a' + 1},
+	{"rc", 'r' - 'a' + 1},
+}
+
+// versionComponent - number, letter, number triple of a PORTVERSION, e.g. "0a1" → {0, 1, 1}
+type versionComponent struct {
+	n, a, pl int
+}
+
+func isDigit(b byte) bool {
+	return '0' <= b && b <= '9'
+}
+
+func isAlpha(b byte) bool {
+	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+// leadingInt parses the leading decimal digits of s like strtoul(3) does, saturating on overflow.
+// It returns the number and the rest of the string.
+func leadingInt(s string) (int, string) {
+	n, i := 0, 0
+	for ; i < len(s) && isDigit(s[i]); i++ {
+		if n < (1<<62)/10 {
+			n = n*10 + int(s[i]-'0')
+		}
+	}
+	return n, s[i:]
+}
+
+// nextComponent parses the next component of a PORTVERSION and returns it with the rest of the string,
+// following get_component() of pkg(8):
+// a leading number (-1 if absent, -2 for "*"), a letter or a special word (see versionStages),
+// and a patch number (-1 if absent after a letter); trailing separators are skipped.
+// A special word right after a number is treated as a start of the next component ("1.0alpha1" = "1.0.alpha1").
+func nextComponent(s string) (versionComponent, string) {
+	var c versionComponent
+	hasStage, hasPatchLevel := false, false
+
+	switch {
+	case s != "" && isDigit(s[0]):
+		c.n, s = leadingInt(s)
+	case s != "" && s[0] == '*':
+		c.n = -2
+		if i := strings.IndexByte(s, '+'); i > 0 {
+			s = s[i:]
+		} else {
+			s = ""
+		}
+	default:
+		c.n, hasStage = -1, true
+	}
+
+	if s != "" && isAlpha(s[0]) {
+		letter := true
+		hasPatchLevel = true
+		for _, st := range versionStages {
+			if l := len(st.name); len(s) >= l && strings.EqualFold(s[:l], st.name) && (len(s) == l || !isAlpha(s[l])) {
+				if hasStage {
+					c.a, s = st.value, s[l:]
+				} else {
+					hasPatchLevel = false // insert dot
+				}
+				letter = false
+				break
+			}
+		}
+		if letter { // use the first letter and skip following
+			i := 1
+			for i < len(s) && isAlpha(s[i]) {
+				i++
+			}
+			c.a, s = int(ut.ToLowerASCII(s[0])-'a')+1, s[i:]
+		}
+	}
+
+	if hasPatchLevel {
+		if s != "" && isDigit(s[0]) {
+			c.pl, s = leadingInt(s)
+		} else {
+			c.pl = -1
+		}
+	}
+
+	// skip trailing separators
+	for s != "" && !isDigit(s[0]) && !isAlpha(s[0]) && s[0] != '+' && s[0] != '*' {
+		s = s[1:]
+	}
+
+	return c, s
+}
+
+// ComparePortVersion compares two PORTVERSION strings (without revision and epoch)
+// component by component as pkg(8) does. Returns -1 if 'a' is older than 'b', 1 if newer, 0 if equal.
+// Examples: "1.0alpha1" < "1.0" < "1.0a" < "1.0b"; "1.0.a" < "1.0"; "*" < "0"; "1.0" < "1.0+2" < "1.0.1".
+func ComparePortVersion(a, b string) int {
+	if strings.EqualFold(a, b) {
+		return 0
+	}
+	for a != "" || b != "" {
+		var ca, cb versionComponent
+		blockA, blockB := a == "" || a[0] == '+', b == "" || b[0] == '+'
+		if !blockA {
+			ca, a = nextComponent(a)
+		}
+		if !blockB {
+			cb, b = nextComponent(b)
+		}
+		switch {
+		case blockA && blockB:
+			if a != "" {
+				a = a[1:]
+			}
+			if b != "" {
+				b = b[1:]
+			}
+		case ca.n != cb.n:
+			return cmp.Compare(ca.n, cb.n)
+		case ca.a != cb.a:
+			return cmp.Compare(ca.a, cb.a)
+		case ca.pl != cb.pl:
+			return cmp.Compare(ca.pl, cb.pl)
+		}
+	}
+	return 0
+}
+
+// splitVersion splits a package name or a bare version string into PORTVERSION, PORTREVISION and PORTEPOCH
+// following split_version() of pkg(8). Example: splitVersion("pkg-1.2.3_4,5") → ("1.2.3", 4, 5)
+func splitVersion(s string) (string, int, int) {
+	if i := strings.LastIndexByte(s, '-'); i >= 0 {
+		s = s[i+1:]
+	}
+
+	revision, epoch, end := 0, 0, -1
+	if i := strings.LastIndexByte(s, '_'); i >= 0 {
+		if !strings.Contains(s[i:], ".") {
+			revision, _ = leadingInt(strings.TrimPrefix(s[i+1:], "+")) // strtoul(3) accepts a sign
+		}
+		end = i
+	}
+
+	rest := s
+	if end >= 0 {
+		rest = s[end+1:]
+	}
+	if i := strings.LastIndexByte(rest, ','); i >= 0 {
+		if !strings.Contains(rest[i:], ".") {
+			epoch, _ = leadingInt(strings.TrimPrefix(rest[i+1:], "+"))
+		}
+		if end < 0 {
+			end = i
+		}
+	}
+
+	if end >= 0 {
+		s = s[:end]
+	}
+	return s, revision, epoch
+}
+
+// CompareStrings compares two package names or version strings exactly as "pkg version -t" does.
+// Returns -1 if 'a' is older than 'b', 1 if newer, 0 if equal.
+func CompareStrings(a, b string) int {
+	va, ra, ea := splitVersion(a)
+	vb, rb, eb := splitVersion(b)
+	return Version{va, ra, ea}.Compare(Version{vb, rb, eb})
+}
@@ -0,0 +1,393 @@
+package portver
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestIntSuffix(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		sep      byte
+		wantInt  int
+		wantRest string
+	}{
+		{
+			name:     "Standard comma suffix",
+			input:    "pkg-1.2.3_4,5",
+			sep:      ',',
+			wantInt:  5,
+			wantRest: "pkg-1.2.3_4",
+		},
+		{
+			name:     "Standard underscore suffix",
+			input:    "pkg-1.2.3_4",
+			sep:      '_',
+			wantInt:  4,
+			wantRest: "pkg-1.2.3",
+		},
+		{
+			name:     "No separator present",
+			input:    "pkg-1.2.3",
+			sep:      ',',
+			wantInt:  0,
+			wantRest: "pkg-1.2.3",
+		},
+		{
+			name:     "Separator at end with no number",
+			input:    "pkg-1.2.3_,",
+			sep:      ',',
+			wantInt:  0,
+			wantRest: "pkg-1.2.3_",
+		},
+		{
+			name:     "Non-numeric suffix",
+			input:    "pkg-1.2.3_abc",
+			sep:      '_',
+			wantInt:  0,
+			wantRest: "pkg-1.2.3",
+		},
+		{
+			name:     "Multiple separators, only last matters",
+			input:    "pkg-1.2.3_4_5",
+			sep:      '_',
+			wantInt:  5,
+			wantRest: "pkg-1.2.3_4",
+		},
+		{
+			name:     "Separator at beginning",
+			input:    "_5",
+			sep:      '_',
+			wantInt:  5,
+			wantRest: "",
+		},
+		{
+			name:     "Empty string",
+			input:    "",
+			sep:      ',',
+			wantInt:  0,
+			wantRest: "",
+		},
+		{
+			name:     "Only separator",
+			input:    ",",
+			sep:      ',',
+			wantInt:  0,
+			wantRest: "",
+		},
+		{
+			name:     "Separator with negative number (invalid)",
+			input:    "pkg-1.2.3_-5",
+			sep:      '_',
+			wantInt:  0, // strconv.Atoi("-5") is valid, but if you want to reject negatives, this would need logic
+			wantRest: "pkg-1.2.3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotInt, gotRest := intSuffix(tt.input, tt.sep)
+			if gotInt != tt.wantInt || gotRest != tt.wantRest {
+				t.Errorf("intSuffix(%q, %q) = (%d, %q); want (%d, %q)",
+					tt.input, tt.sep, gotInt, gotRest, tt.wantInt, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in              string
+		version         string
+		revision, epoch int
+		components      []string
+		str             string
+		wantErr         bool
+	}{
+		{"1.2.3", "1.2.3", 0, 0, []string{"1", "2", "3"}, "1.2.3", false},
+		{"1.2.3_4", "1.2.3", 4, 0, []string{"1", "2", "3"}, "1.2.3_4", false},
+		{"1.2.3,5", "1.2.3", 0, 5, []string{"1", "2", "3"}, "1.2.3,5", false},
+		{"1.2.3_4,5", "1.2.3", 4, 5, []string{"1", "2", "3"}, "1.2.3_4,5", false},
+		{"1.2a", "1.2a", 0, 0, []string{"1", "2a"}, "1.2a", false},
+		{"1.2.3_,7", "1.2.3", 0, 7, []string{"1", "2", "3"}, "1.2.3,7", false},
+		{"1.2.3_4,", "1.2.3", 4, 0, []string{"1", "2", "3"}, "1.2.3_4", false},
+		{"", "", 0, 0, nil, "", true},
+		{"_1,1", "", 0, 0, nil, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			v, err := Parse(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if v.PortVersion() != tt.version || v.PortRevision() != tt.revision || v.PortEpoch() != tt.epoch {
+				t.Errorf("Parse(%q) = (%q, %d, %d); want (%q, %d, %d)",
+					tt.in, v.PortVersion(), v.PortRevision(), v.PortEpoch(), tt.version, tt.revision, tt.epoch)
+			}
+			if got := v.Components(); !slices.Equal(got, tt.components) {
+				t.Errorf("Components() = %q; want %q", got, tt.components)
+			}
+			if got := v.String(); got != tt.str {
+				t.Errorf("String() = %q; want %q", got, tt.str)
+			}
+			if v.IsZero() != tt.wantErr {
+				t.Errorf("IsZero() = %v", v.IsZero())
+			}
+		})
+	}
+}
+
+func TestSplitFile(t *testing.T) {
+	tests := []struct {
+		name, input, wantName, wantVersion string
+		wantOK                             bool
+	}{
+		{"Standard .pkg with revision and epoch", "/usr/ports/pkg-name-1.2.3_4,5.pkg", "pkg-name", "1.2.3_4,5", true},
+		{"No revision, only epoch", "pkg-1.2.3_,7.txz", "pkg", "1.2.3,7", true},
+		{"No epoch, only revision", "pkg-1.2.3_4,.txz", "pkg", "1.2.3_4", true},
+		{"No revision and epoch", "pkg-1.2.3.txz", "pkg", "1.2.3", true},
+		{"Long version string", "pkg-10.20.30.40_99,88.pkg", "pkg", "10.20.30.40_99,88", true},
+		{"Single digit version", "pkg-1_1,1.pkg", "pkg", "1_1,1", true},
+		{"No dash (no key)", "1.2.3_4,5.pkg", "", "", false},
+		{"Empty string", "", "", "", false},
+		{"Only dash", "-1.2.3_4,5.pkg", "", "", false},
+		{"Empty version", "pkg-.pkg", "", "", false},
+		{"Malformed version string", "pkg-1.2a.3_4,5.pkg", "pkg", "1.2a.3_4,5", true},
+		{"No extension", "pkg-1.2.3_4,5", "pkg", "1.2.3_4,5", true},
+		{"Dot in extension but all digits", "pkg-1.2.3.123", "pkg", "1.2.3.123", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, v, ok := SplitFile(tt.input)
+			if name != tt.wantName || v.String() != tt.wantVersion || ok != tt.wantOK {
+				t.Errorf("SplitFile(%q) = (%q, %q, %v); want (%q, %q, %v)",
+					tt.input, name, v, ok, tt.wantName, tt.wantVersion, tt.wantOK)
+			}
+		})
+	}
+}
+
+// Expected results are those of "pkg version -t a b".
+var pkgVersionTests = []struct {
+	a, b string
+	want byte
+}{
+	{"1", "0", '>'},
+	{"1", "1", '='},
+	{"0", "1", '<'},
+	{"1.1", "1.0", '>'},
+	{"1.0", "1.1", '<'},
+	{"1.0", "1.0.0", '='},
+	{"1.0.1", "1.0", '>'},
+	{"0.031", "0.29", '>'},
+	{"1.10", "1.9", '>'},
+	{"1.0.a", "1.0.b", '<'},
+	{"1.0.b", "1.0.a", '>'},
+	{"1.0a", "1.0b", '<'},
+	{"1.0a", "1.0", '>'},
+	{"1.0.a", "1.0", '<'},
+	{"1.0.a", "1.0.1", '<'},
+	{"1.0a1", "1.0a2", '<'},
+	{"1.0a", "1.0a1", '<'},
+	{"1.0A", "1.0a", '='},
+	{"2.0.p1", "2.0", '<'},
+	{"2.0p1", "2.0", '>'},
+	{"2.0p1", "2.0.1", '>'},
+	{"2.0pl1", "2.0", '<'},
+	{"2.0pl1", "2.0.1", '<'},
+	{"2.0.pl1", "2.0", '<'},
+	{"2.0.pl1", "2.0.0", '<'},
+	{"2.0.pl1", "2.0p1", '<'},
+	{"1.0alpha1", "1.0", '<'},
+	{"1.0alpha1", "1.0alpha2", '<'},
+	{"1.0alpha2", "1.0beta1", '<'},
+	{"1.0beta1", "1.0pre1", '<'},
+	{"1.0pre1", "1.0rc1", '<'},
+	{"1.0rc1", "1.0", '<'},
+	{"1.0rc1", "1.0rc2", '<'},
+	{"1.0RC1", "1.0rc1", '='},
+	{"1.0.alpha1", "1.0.a1", '='},
+	{"1.0alpha", "1.0a", '<'},
+	{"1.0beta", "1.0b", '<'},
+	{"1.0pre", "1.0p", '<'},
+	{"1.0rc", "1.0r", '<'},
+	{"1.0alpha1", "1.0a1", '<'},
+	{"1.0a1", "1.0.a1", '>'},
+	{"1.0alphabet", "1.0a", '='},
+	{"1.0prerelease", "1.0p", '='},
+	{"1.0rcx", "1.0r", '='},
+	{"20250101", "20241231", '>'},
+	{"20250101rc2", "20250101", '<'},
+	{"20250101rc2", "20250101rc10", '<'},
+	{"20250101rc2", "20250102", '<'},
+	{"20250101rc2", "20250101.1", '<'},
+	{"20250101.rc2", "20250101rc2", '='},
+	{"1.0-rc1", "1.0", '<'},
+	{"*", "0", '<'},
+	{"*", "1.0", '<'},
+	{"1.*", "1.0", '<'},
+	{"1.*", "1.9999", '<'},
+	{"1.*", "2", '<'},
+	{"1.*+2", "1.*", '>'},
+	{"1.0+2", "1.0", '>'},
+	{"1.0+2", "1.0.1", '<'},
+	{"1.0+2", "1.0+3", '<'},
+	{"1.0+2.1", "1.0+2", '>'},
+	{"1.0", "1.0_1", '<'},
+	{"1.0_1", "1.0_2", '<'},
+	{"1.0_10", "1.0_9", '>'},
+	{"1.0_1", "1.0,1", '<'},
+	{"1.0,1", "0.9_9,1", '>'},
+	{"2.0,1", "1.0,2", '<'},
+	{"1.0_1,1", "1.0,1", '>'},
+	{"1.0_1,1", "1.0_2,1", '<'},
+	{"pkg-1.2.3", "pkg-1.2.4", '<'},
+	{"pkg-1.2.3_4,5", "pkg-1.2.3_4,5", '='},
+	{"foo-bar-1.0", "foo-1.0", '='},
+	{"1.0.0.0", "1", '='},
+	{"1.00", "1.0", '='},
+	{"01", "1", '='},
+	{"1.2.3.4.5", "1.2.3.4.6", '<'},
+	{"1.0a1b2", "1.0a1", '<'},
+	{"1.0ab", "1.0a", '='},
+	{"1.0a.1", "1.0a", '>'},
+	{"1_2.3", "1_3", '<'},
+	{"1.0_1.0", "1.0", '='},
+	{"1,2.3", "1", '='},
+	{"1.0g", "1.0.7", '>'},
+	{"3.0.0.b1", "3.0.0", '<'},
+	{"3.0.0b1", "3.0.0", '>'},
+	{"3.0.0b1", "3.0.0.1", '>'},
+	{"0.9.8zh", "0.9.8zg", '='},
+	{"0.9.8zh", "1.0.0", '<'},
+	{"1.0.2u", "1.0.2", '>'},
+	{"1.1.1w", "1.1.1", '>'},
+	{"1.1.1w", "3.0.0", '<'},
+	{"r1234", "r1235", '<'},
+	{"g20250101", "g20241231", '>'},
+	{"v1.0", "1.0", '<'},
+	{"5.2.37", "5.2.37", '='},
+	{"5.2.37", "5.2.36", '>'},
+	{"8.11.1", "8.11.1_1", '<'},
+	{"2.47.1", "2.47.1,1", '<'},
+	{"1.21.3,1", "1.22.0", '>'},
+	{"0.0.20250101", "0.0.20241231", '>'},
+	{"1.0.0.20250101", "1.0.0", '>'},
+	{"1.0beta10", "1.0beta9", '>'},
+	{"1.0.beta10", "1.0beta10", '='},
+	{"4.0.r1", "4.0.1", '<'},
+	{"4.0.r1", "4.0", '<'},
+	{"1.0p1", "1.0pre1", '>'},
+	{"1.0pl", "1.0", '<'},
+	{"1.0.pl", "1.0", '<'},
+}
+
+func TestCompareStrings(t *testing.T) {
+	sign := map[int]byte{-1: '<', 0: '=', 1: '>'}
+	mirror := map[byte]byte{'<': '>', '=': '=', '>': '<'}
+	for _, tt := range pkgVersionTests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			if got := sign[CompareStrings(tt.a, tt.b)]; got != tt.want {
+				t.Errorf("CompareStrings(%q, %q) = %c; want %c", tt.a, tt.b, got, tt.want)
+			}
+			if got := sign[CompareStrings(tt.b, tt.a)]; got != mirror[tt.want] {
+				t.Errorf("CompareStrings(%q, %q) = %c; want %c", tt.b, tt.a, got, mirror[tt.want])
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	want := []string{ // newest first
+		"2.0,1",
+		"1.0b_1",
+		"1.0b",
+		"1.0a2",
+		"1.0a", // a letter right after a number is newer than the next component
+		"1.0.1",
+		"1.0_2",
+		"1.0_1",
+		"1.0",
+		"1.0rc2",
+		"1.0rc1",
+		"1.0beta1",
+		"1.0alpha2",
+		"1.0alpha1",
+		"1.0.a",
+		"0.31",
+		"0.29",
+		"0.9",
+	}
+
+	versions := make([]Version, 0, len(want))
+	for i := len(want) - 1; i >= 0; i-- {
+		v, err := Parse(want[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions = append(versions, v)
+	}
+
+	slices.SortFunc(versions, func(a, b Version) int { return Compare(b, a) })
+
+	for i := range versions {
+		if got := versions[i].String(); got != want[i] {
+			t.Errorf("position %d = %q; want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		versions   map[string]bool
+	}{
+		{">=1.2_1,1 <2,1", map[string]bool{"1.2_1,1": true, "1.9,1": true, "1.2,1": false, "1.3": false, "2,1": false}},
+		{">=1.2_1,1 <2", map[string]bool{"1.2_1,1": false, "1.9,1": false, "1.9": false}}, // PORTEPOCH always wins
+		{">=1.2 <2", map[string]bool{"1.2": true, "1.10": true, "1.2alpha1": false, "2.0": false, "2.0rc1": true}},
+		{"1.0", map[string]bool{"1.0": true, "1.0.0": true, "1.0_1": false}},
+		{"==1.0_1", map[string]bool{"1.0_1": true, "1.0": false}},
+		{"!=1.0 >0.9", map[string]bool{"1.0": false, "1.1": true, "0.9": false}},
+		{"<=1.0 || >=3", map[string]bool{"0.5": true, "1.0": true, "2.0": false, "3.0": true}},
+		{">1.0a", map[string]bool{"1.0b": true, "1.0.1": false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			c, err := ParseConstraint(tt.constraint)
+			if err != nil {
+				t.Fatalf("ParseConstraint(%q) error = %v", tt.constraint, err)
+			}
+			for s, want := range tt.versions {
+				v, err := Parse(s)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := c.Check(v); got != want {
+					t.Errorf("%q.Check(%q) = %v; want %v", c, s, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConstraintString(t *testing.T) {
+	c, err := ParseConstraint(" >=1.2_1,1   <2||1.0 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.String(), ">=1.2_1,1 <2 || =1.0"; got != want {
+		t.Errorf("String() = %q; want %q", got, want)
+	}
+}
+
+func TestParseConstraintInvalid(t *testing.T) {
+	for _, s := range []string{"", "   ", ">=", ">=1.0 ||", "<_1"} {
+		if _, err := ParseConstraint(s); err == nil {
+			t.Errorf("ParseConstraint(%q) expected an error", s)
+		}
+	}
+}